@@ -23,7 +23,7 @@ func setupAuthCheckCommand() *cobra.Command {
 		Use:   "check",
 		Short: "Verify GitHub authentication",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			username, err := utils.GetGhUsernameGraphQL()
+			username, err := utils.GetGhUsernameGraphQL(cmd.Context())
 			if err != nil {
 				fmt.Println("❌ Failed to fetch user info.")
 				fmt.Println(err)
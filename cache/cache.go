@@ -1,31 +1,85 @@
 package cache
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"strings"
+	"slices"
+	"time"
 
 	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/utils"
 )
 
-type prCacheData map[string][]models.PR
+// cacheVersion is bumped whenever the file layout changes. Files written in
+// another layout are discarded.
+const cacheVersion = 4
 
-type Fetcher func(client models.GQLClient, owner, repo, branch string, limit int) ([]models.PR, error)
+type prCacheData struct {
+	Version int                   `json:"version"`
+	Repos   map[string]*repoCache `json:"repos"`
+}
+
+// repoCache holds the commit records of one repository and scope, keyed by
+// SHA, and the scanned histories of its branches as lists of those SHAs.
+type repoCache struct {
+	Branches map[string]branchLog           `json:"branches"`
+	Commits  map[string]models.CommitRecord `json:"commits"`
+}
+
+// branchLog is a scanned branch history, newest first. Since is the commit
+// the scan stopped at, empty when it ran to the start of the history or to
+// the limit.
+type branchLog struct {
+	Head      string    `json:"head"`
+	Since     string    `json:"since,omitempty"`
+	Oids      []string  `json:"oids"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ErrIncomplete is returned by a Fetcher, wrapped, together with records it
+// could not fill in completely. Those records are used but never stored, so
+// the next scan fetches them again.
+var ErrIncomplete = errors.New("incomplete commit records")
+
+// Fetcher scans a branch from its head, stopping before any commit in stopAt.
+type Fetcher func(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string) ([]models.CommitRecord, error)
 type HashGetter func(branchRef string) (string, error)
+type AncestryChecker func(ancestor, descendant string) bool
 type PathGetter func() (string, error)
 
-func FetchPRsWithCache(client models.GQLClient, owner, repo, branch string, limit int, isLocal bool, fetcher Fetcher, hashGetter HashGetter, pathGetter PathGetter) ([]models.PR, error) {
+// FetchCommitsWithCache returns the commits of a branch since the given merge
+// base, or its whole history when since is empty. A cached history is reused
+// when the head has not moved. When the head moved forward, only the commits
+// after the cached head are fetched and put in front of the cached ones.
+// Sparse histories, such as those filtered by path, skip commits, so their
+// parents cannot be followed and they are cut at the first stop commit
+// instead. Nothing is stored when ctx is cancelled before the fetch completes,
+// or when the fetcher reports ErrIncomplete.
+func FetchCommitsWithCache(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, isLocal, sparse bool, scope, since string, fetcher Fetcher, hashGetter HashGetter, ancestryChecker AncestryChecker, pathGetter PathGetter) ([]models.CommitRecord, error) {
 	branchRef := branch
 	if !isLocal {
-		branchRef = "origin/" + branch
+		branchRef = utils.RemoteTrackingRevision(branch)
+	}
+
+	complete := true
+	fetch := func(stopAt []string) ([]models.CommitRecord, error) {
+		records, err := fetcher(ctx, client, owner, repo, branch, limit, stopAt)
+		if errors.Is(err, ErrIncomplete) {
+			complete = false
+			return records, nil
+		}
+
+		return records, err
 	}
 
 	hash, err := hashGetter(branchRef)
 	if err != nil {
-		return fetcher(client, owner, repo, branch, limit)
+		return fetch(stopList(since))
 	}
 
 	cache, err := loadCache(pathGetter)
@@ -33,29 +87,217 @@ func FetchPRsWithCache(client models.GQLClient, owner, repo, branch string, limi
 		return nil, fmt.Errorf("could not load cache: %w", err)
 	}
 
-	cacheKey := fmt.Sprintf("%s/%s:%s@%s", owner, repo, branch, hash)
-	if cachedPRs, found := cache[cacheKey]; found {
-		return cachedPRs, nil
+	repoKey := repoCacheKey(owner, repo, scope, isLocal)
+	entry := cache.repo(repoKey)
+	branchKey := branchCacheKey(branch, limit)
+
+	log, found := entry.Branches[branchKey]
+	if found && log.covers(since) && entry.hasRecords(log.Oids) {
+		if log.Head == hash {
+			return entry.records(log.Oids, since, sparse), nil
+		}
+
+		if ancestryChecker(log.Head, hash) {
+			fresh, err := fetch(log.resumePoints())
+			if err != nil {
+				return nil, err
+			}
+
+			log = branchLog{Head: hash, Since: log.Since, Oids: mergeOids(entry.add(fresh), log.Oids), UpdatedAt: time.Now()}
+			if limit > 0 && len(log.Oids) > limit {
+				log.Oids = log.Oids[:limit]
+			}
+			if complete {
+				storeLog(pathGetter, repoKey, branchKey, log, entry.records(log.Oids, "", sparse))
+			}
+
+			return entry.records(log.Oids, since, sparse), nil
+		}
 	}
 
-	livePRs, err := fetcher(client, owner, repo, branch, limit)
+	fresh, err := fetch(stopList(since))
 	if err != nil {
 		return nil, err
 	}
 
-	prefixToClean := fmt.Sprintf("%s/%s:%s@", owner, repo, branch)
-	for k := range cache {
-		if strings.HasPrefix(k, prefixToClean) {
-			delete(cache, k)
+	if complete {
+		log = branchLog{Head: hash, Since: since, Oids: entry.add(fresh), UpdatedAt: time.Now()}
+		storeLog(pathGetter, repoKey, branchKey, log, fresh)
+	}
+
+	return fresh, nil
+}
+
+func repoCacheKey(owner, repo, scope string, isLocal bool) string {
+	key := fmt.Sprintf("%s/%s", owner, repo)
+	if isLocal {
+		key = "local:" + key
+	}
+	if scope != "" {
+		key += "[" + scope + "]"
+	}
+
+	return key
+}
+
+// branchCacheKey keeps limited scans apart from complete ones, so a limited
+// history is never served as the full one.
+func branchCacheKey(branch string, limit int) string {
+	if limit > 0 {
+		return fmt.Sprintf("%s|limit=%d", branch, limit)
+	}
+
+	return branch
+}
+
+func stopList(since string) []string {
+	if since == "" {
+		return nil
+	}
+
+	return []string{since}
+}
+
+// covers reports whether the log holds every commit a scan stopping at since
+// would return. A log that did not stop at a merge base holds the newest
+// commits of the branch, so it covers any since.
+func (l branchLog) covers(since string) bool {
+	if l.Since == "" || l.Since == since {
+		return true
+	}
+
+	for _, oid := range l.Oids {
+		if oid == since {
+			return true
 		}
 	}
 
-	cache[cacheKey] = livePRs
-	if err := saveCache(cache, pathGetter); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to save cache: %v\n", err)
+	return false
+}
+
+// resumePoints are the commits a scan from a newer head stops at: the old
+// head, whose history is cached, and the commit the cached scan stopped at,
+// so a merge that brings in older commits does not pull in what lies before
+// it. The newest cached commit is added because a history filtered by path
+// does not list a head that leaves the path alone.
+func (l branchLog) resumePoints() []string {
+	candidates := []string{l.Head, l.Since}
+	if len(l.Oids) > 0 {
+		candidates = append(candidates, l.Oids[0])
+	}
+
+	var points []string
+	for _, oid := range candidates {
+		if oid != "" && !slices.Contains(points, oid) {
+			points = append(points, oid)
+		}
+	}
+
+	return points
+}
+
+func mergeOids(newer, older []string) []string {
+	seen := make(map[string]bool, len(newer))
+	for _, oid := range newer {
+		seen[oid] = true
+	}
+
+	merged := newer
+	for _, oid := range older {
+		if !seen[oid] {
+			merged = append(merged, oid)
+		}
+	}
+
+	return merged
+}
+
+func (c *prCacheData) repo(key string) *repoCache {
+	entry, ok := c.Repos[key]
+	if !ok {
+		entry = &repoCache{}
+		c.Repos[key] = entry
+	}
+	if entry.Branches == nil {
+		entry.Branches = make(map[string]branchLog)
+	}
+	if entry.Commits == nil {
+		entry.Commits = make(map[string]models.CommitRecord)
 	}
 
-	return livePRs, nil
+	return entry
+}
+
+func (r *repoCache) hasRecords(oids []string) bool {
+	for _, oid := range oids {
+		if _, ok := r.Commits[oid]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// records returns the records of oids that are not reachable from since,
+// following the parents of each record. A sparse history is cut at since
+// instead.
+func (r *repoCache) records(oids []string, since string, sparse bool) []models.CommitRecord {
+	exclusion := utils.NewExclusion(stopList(since))
+
+	var records []models.CommitRecord
+	for _, oid := range oids {
+		record := r.Commits[oid]
+		if sparse {
+			if oid == since {
+				break
+			}
+		} else if !exclusion.Keep(oid, record.ParentOids) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func (r *repoCache) add(records []models.CommitRecord) []string {
+	oids := make([]string, 0, len(records))
+	for _, record := range records {
+		r.Commits[record.Oid] = record
+		oids = append(oids, record.Oid)
+	}
+
+	return oids
+}
+
+// prune drops the commit records no branch history refers to anymore.
+func (r *repoCache) prune() {
+	referenced := make(map[string]bool)
+	for _, log := range r.Branches {
+		for _, oid := range log.Oids {
+			referenced[oid] = true
+		}
+	}
+
+	for oid := range r.Commits {
+		if !referenced[oid] {
+			delete(r.Commits, oid)
+		}
+	}
+}
+
+// storeLog writes one branch history and its records into the cache. The
+// file is read again under the lock, so histories other scans stored in the
+// meantime are kept.
+func storeLog(pathGetter PathGetter, repoKey, branchKey string, log branchLog, records []models.CommitRecord) {
+	err := updateCache(pathGetter, func(data *prCacheData) {
+		entry := data.repo(repoKey)
+		entry.add(records)
+		entry.Branches[branchKey] = log
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save cache: %v\n", err)
+	}
 }
 
 func GetCachePath() (string, error) {
@@ -72,47 +314,101 @@ func GetCachePath() (string, error) {
 	return filepath.Join(toolCachePath, "prs_cache.json"), nil
 }
 
-func GetBranchHeadHash(branchRef string) (string, error) {
-	cmd := exec.Command("git", "rev-parse", branchRef)
-	out, err := cmd.Output()
-	if err != nil {
-		return "", fmt.Errorf("could not get SHA for branch '%s': %w", branchRef, err)
-	}
+// IsAncestor reports whether a cached head is still part of the branch
+// history. It is false after a force push, or when the old head is no longer
+// in the local object database.
+func IsAncestor(ancestor, descendant string) bool {
+	return exec.Command("git", "merge-base", "--is-ancestor", ancestor, descendant).Run() == nil
+}
 
-	return strings.TrimSpace(string(out)), nil
+func newCacheData() *prCacheData {
+	return &prCacheData{Version: cacheVersion, Repos: make(map[string]*repoCache)}
 }
 
-func loadCache(pathGetter PathGetter) (prCacheData, error) {
-	path, err := pathGetter()
-	if err != nil {
-		return nil, err
-	}
+func loadCache(pathGetter PathGetter) (*prCacheData, error) {
+	var data *prCacheData
+	err := withCacheLock(pathGetter, func(path string) error {
+		var err error
+		data, err = readCache(path)
+		return err
+	})
+
+	return data, err
+}
+
+// updateCache applies change to the current content of the cache and writes
+// the result back, all under the cache lock.
+func updateCache(pathGetter PathGetter, change func(data *prCacheData)) error {
+	return withCacheLock(pathGetter, func(path string) error {
+		data, err := readCache(path)
+		if err != nil {
+			return err
+		}
+
+		change(data)
+		for key, entry := range data.Repos {
+			entry.prune()
+			if len(entry.Branches) == 0 {
+				delete(data.Repos, key)
+			}
+		}
+
+		return writeCache(path, data)
+	})
+}
 
-	data := make(prCacheData)
+func readCache(path string) (*prCacheData, error) {
 	content, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return data, nil
+		return newCacheData(), nil
 	}
 	if err != nil {
 		return nil, err
 	}
-	if err := json.Unmarshal(content, &data); err != nil {
-		return make(prCacheData), nil
+	if len(content) == 0 {
+		return newCacheData(), nil
+	}
+
+	data := newCacheData()
+	if err := json.Unmarshal(content, data); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring unreadable cache %s: %v\n", path, err)
+		return newCacheData(), nil
+	}
+	if data.Version != cacheVersion {
+		return newCacheData(), nil
+	}
+	if data.Repos == nil {
+		data.Repos = make(map[string]*repoCache)
 	}
 
 	return data, nil
 }
 
-func saveCache(data prCacheData, pathGetter PathGetter) error {
-	path, err := pathGetter()
+// writeCache replaces the cache file through a temporary file in the same
+// directory, so a crash mid-write never leaves a truncated cache behind.
+func writeCache(path string, data *prCacheData) error {
+	content, err := json.MarshalIndent(data, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	content, err := json.MarshalIndent(data, "", "  ")
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return err
 	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
 
-	return os.WriteFile(path, content, 0644)
+	return os.Rename(tmp.Name(), path)
 }
@@ -1,25 +1,75 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/utils"
 	"github.com/stretchr/testify/assert"
 )
 
-func mockFetcher(client models.GQLClient, owner, repo, branch string, limit int) ([]models.PR, error) {
-	return []models.PR{
-		{Number: 101, Title: "Feat: New API"},
-		{Number: 102, Title: "Fix: Bug"},
-	}, nil
+type fetchCall struct {
+	limit  int
+	stopAt []string
 }
 
-func mockFetcherWithError(client models.GQLClient, owner, repo, branch string, limit int) ([]models.PR, error) {
+// historyFetcher serves a linear branch history, newest first, and records
+// how it was called.
+func historyFetcher(history []string, calls *[]fetchCall) Fetcher {
+	var commits []models.CommitRecord
+	for i, oid := range history {
+		commit := models.CommitRecord{Oid: oid, Message: "Commit " + oid, PR: &models.PR{Number: len(history) - i}}
+		if i+1 < len(history) {
+			commit.ParentOids = []string{history[i+1]}
+		}
+		commits = append(commits, commit)
+	}
+
+	return graphFetcher(commits, calls)
+}
+
+// graphFetcher serves a branch history, newest first, leaving out the commits
+// reachable from the stop commits the way the API scan does.
+func graphFetcher(history []models.CommitRecord, calls *[]fetchCall) Fetcher {
+	return func(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string) ([]models.CommitRecord, error) {
+		*calls = append(*calls, fetchCall{limit: limit, stopAt: stopAt})
+
+		exclusion := utils.NewExclusion(stopAt)
+		var records []models.CommitRecord
+		for _, commit := range history {
+			if exclusion.Done() || (limit > 0 && len(records) >= limit) {
+				break
+			}
+			if exclusion.Keep(commit.Oid, commit.ParentOids) {
+				records = append(records, commit)
+			}
+		}
+
+		return records, nil
+	}
+}
+
+func commitWithParents(oid string, parents ...string) models.CommitRecord {
+	return models.CommitRecord{Oid: oid, Message: "Commit " + oid, Parents: len(parents), ParentOids: parents}
+}
+
+func mockFetcherWithError(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string) ([]models.CommitRecord, error) {
 	return nil, fmt.Errorf("simulated API error")
 }
 
+func hashOf(hash string) HashGetter {
+	return func(branchRef string) (string, error) { return hash, nil }
+}
+
+func alwaysAncestor(ancestor, descendant string) bool { return true }
+
+func neverAncestor(ancestor, descendant string) bool { return false }
+
 func setupTestCache(t *testing.T, initialContent string) (PathGetter, func()) {
 	t.Helper()
 	tmpFile, err := os.CreateTemp("", "test_cache_*.json")
@@ -34,79 +84,287 @@ func setupTestCache(t *testing.T, initialContent string) (PathGetter, func()) {
 	}
 	cleanup := func() {
 		os.Remove(tmpFile.Name())
+		os.Remove(tmpFile.Name() + ".lock")
 	}
 	return pathGetter, cleanup
 }
 
-func TestFetchPRsWithCache(t *testing.T) {
+func oidsOf(records []models.CommitRecord) []string {
+	var oids []string
+	for _, record := range records {
+		oids = append(oids, record.Oid)
+	}
+
+	return oids
+}
+
+func TestFetchCommitsWithCache(t *testing.T) {
 	owner, repo, branch := "my-org", "my-repo", "main"
 
 	t.Run("Cache Miss - fetches, returns data, and saves to cache", func(t *testing.T) {
 		pathGetter, cleanup := setupTestCache(t, "")
 		defer cleanup()
-		mockHashGetter := func(branchRef string) (string, error) { return "somehash", nil }
+		var calls []fetchCall
 
-		prs, err := FetchPRsWithCache(nil, owner, repo, branch, 0, false, mockFetcher, mockHashGetter, pathGetter)
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c2", "c1"}, &calls), hashOf("c2"), alwaysAncestor, pathGetter)
 		assert.NoError(t, err)
-		assert.Len(t, prs, 2)
-		assert.Equal(t, 101, prs[0].Number)
+		assert.Equal(t, []string{"c2", "c1"}, oidsOf(records))
+		assert.Len(t, calls, 1)
 
 		cachePath, _ := pathGetter()
 		content, err := os.ReadFile(cachePath)
 		assert.NoError(t, err)
-		assert.Contains(t, string(content), "101")
-		assert.Contains(t, string(content), "102")
-		assert.NotContains(t, string(content), `"number"`) 
+		assert.Contains(t, string(content), `"my-org/my-repo"`)
+		assert.Contains(t, string(content), `"head": "c2"`)
 	})
 
 	t.Run("Cache Hit - returns data from cache without calling fetcher", func(t *testing.T) {
-		hash := "abcdef12345"
-		cacheKey := fmt.Sprintf("%s/%s:%s@%s", owner, repo, branch, hash)
-		initialContent := fmt.Sprintf(`{ "%s": [301, 302] }`, cacheKey)
-		pathGetter, cleanup := setupTestCache(t, initialContent)
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+		fetcher := historyFetcher([]string{"c2", "c1"}, &calls)
+
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", fetcher, hashOf("c2"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", fetcher, hashOf("c2"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c2", "c1"}, oidsOf(records))
+		assert.Equal(t, 2, records[0].PR.Number)
+		assert.Len(t, calls, 1)
+	})
+
+	t.Run("Moved head - only fetches the new commits", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c2", "c1"}, &calls), hashOf("c2"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c4", "c3", "c2", "c1"}, &calls), hashOf("c4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c4", "c3", "c2", "c1"}, oidsOf(records))
+		assert.Len(t, calls, 2)
+		assert.Equal(t, []string{"c2"}, calls[1].stopAt)
+
+		records, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher(nil, &calls), hashOf("c4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Len(t, records, 4)
+		assert.Len(t, calls, 2)
+	})
+
+	t.Run("Rewritten history - fetches everything again", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c2", "c1"}, &calls), hashOf("c2"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"x2", "c1"}, &calls), hashOf("x2"), neverAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"x2", "c1"}, oidsOf(records))
+		assert.Nil(t, calls[1].stopAt)
+
+		cachePath, _ := pathGetter()
+		content, err := os.ReadFile(cachePath)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(content), `"c2"`)
+	})
+
+	t.Run("Limited scans are kept apart and stay limited", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 2, false, false, "", "", historyFetcher([]string{"c3", "c2", "c1"}, &calls), hashOf("c3"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c3", "c2"}, oidsOf(records))
+
+		records, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c3", "c2", "c1"}, &calls), hashOf("c3"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c3", "c2", "c1"}, oidsOf(records))
+		assert.Len(t, calls, 2)
+
+		records, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 2, false, false, "", "", historyFetcher([]string{"c4", "c3", "c2", "c1"}, &calls), hashOf("c4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c4", "c3"}, oidsOf(records))
+	})
+
+	t.Run("Merge base inside the cached history - cuts it without fetching", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
 		defer cleanup()
+		var calls []fetchCall
+		fetcher := historyFetcher([]string{"c4", "c3", "c2", "c1"}, &calls)
+
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "c1", fetcher, hashOf("c4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "c3", fetcher, hashOf("c4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c4"}, oidsOf(records))
+		assert.Len(t, calls, 1)
+
+		_, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "c0", fetcher, hashOf("c4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Len(t, calls, 2)
+	})
+
+	t.Run("Merge base inside the cached history - keeps merged commits older than it", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+		fetcher := graphFetcher([]models.CommitRecord{
+			commitWithParents("m4", "c3", "x1"),
+			commitWithParents("c3", "c2"),
+			commitWithParents("c2", "c1"),
+			commitWithParents("x1", "c1"),
+			commitWithParents("c1"),
+		}, &calls)
+
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", fetcher, hashOf("m4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "c2", fetcher, hashOf("m4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"m4", "c3", "x1"}, oidsOf(records))
+		assert.Len(t, calls, 1)
+	})
 
-		mockHashGetter := func(branchRef string) (string, error) { return hash, nil }
-		failingFetcher := func(client models.GQLClient, owner, repo, branch string, limit int) ([]models.PR, error) {
-			t.Fatal("fetcher was called on a cache hit")
-			return nil, nil
+	t.Run("Moved head - fetches merged commits older than the merge base", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+		history := []models.CommitRecord{
+			commitWithParents("m4", "c3", "x1"),
+			commitWithParents("c3", "c2"),
+			commitWithParents("c2", "c1"),
+			commitWithParents("x1", "c1"),
+			commitWithParents("c1"),
 		}
 
-		prs, err := FetchPRsWithCache(nil, owner, repo, branch, 0, false, failingFetcher, mockHashGetter, pathGetter)
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "c2", graphFetcher(history[1:], &calls), hashOf("c3"), alwaysAncestor, pathGetter)
 		assert.NoError(t, err)
-		assert.Len(t, prs, 2)
-		assert.Equal(t, 301, prs[0].Number)
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "c2", graphFetcher(history, &calls), hashOf("m4"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"m4", "x1", "c3"}, oidsOf(records))
+		assert.Len(t, calls, 2)
+		assert.Equal(t, []string{"c3", "c2"}, calls[1].stopAt)
 	})
 
-	t.Run("Cache Miss due to different SHA - fetches and updates cache", func(t *testing.T) {
-		oldHash := "oldhash123"
-		cacheKey := fmt.Sprintf("%s/%s:%s@%s", owner, repo, branch, oldHash)
-		initialContent := fmt.Sprintf(`{ "%s": [301, 302] }`, cacheKey)
-		pathGetter, cleanup := setupTestCache(t, initialContent)
+	t.Run("Sparse history - cuts at the merge base", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
 		defer cleanup()
+		var calls []fetchCall
+		fetcher := graphFetcher([]models.CommitRecord{
+			commitWithParents("c5", "c4"),
+			commitWithParents("c3", "c2"),
+			commitWithParents("c1", "c0"),
+		}, &calls)
 
-		newHash := "newhash456"
-		mockHashGetter := func(branchRef string) (string, error) { return newHash, nil }
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, true, "filter:path=src", "", fetcher, hashOf("c5"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, true, "filter:path=src", "c3", fetcher, hashOf("c5"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c5"}, oidsOf(records))
+		assert.Len(t, calls, 1)
+	})
+
+	t.Run("Cache Miss due to different scope - fetches and keeps both entries", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+		fetcher := historyFetcher([]string{"c1"}, &calls)
 
-		prs, err := FetchPRsWithCache(nil, owner, repo, branch, 0, false, mockFetcher, mockHashGetter, pathGetter)
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", fetcher, hashOf("c1"), alwaysAncestor, pathGetter)
 		assert.NoError(t, err)
-		assert.Len(t, prs, 2)
-		assert.Equal(t, 101, prs[0].Number)
+		_, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "squash,merge", "", fetcher, hashOf("c1"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		_, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, true, false, "squash,merge", "", fetcher, hashOf("c1"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Len(t, calls, 3)
 
 		cachePath, _ := pathGetter()
 		content, err := os.ReadFile(cachePath)
 		assert.NoError(t, err)
-		assert.Contains(t, string(content), newHash)
-		assert.NotContains(t, string(content), oldHash)
+		assert.Contains(t, string(content), `"my-org/my-repo"`)
+		assert.Contains(t, string(content), `"my-org/my-repo[squash,merge]"`)
+		assert.Contains(t, string(content), `"local:my-org/my-repo[squash,merge]"`)
+	})
+
+	t.Run("Cache in an older layout is discarded", func(t *testing.T) {
+		initialContent := fmt.Sprintf(`{ "%s/%s:%s@c1": {"prs": [{"number": 301, "title": "Old"}]} }`, owner, repo, branch)
+		pathGetter, cleanup := setupTestCache(t, initialContent)
+		defer cleanup()
+		var calls []fetchCall
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c1"}, &calls), hashOf("c1"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c1"}, oidsOf(records))
+		assert.Len(t, calls, 1)
 	})
 
 	t.Run("Fetcher returns an error", func(t *testing.T) {
 		pathGetter, cleanup := setupTestCache(t, "")
 		defer cleanup()
-		mockHashGetter := func(branchRef string) (string, error) { return "somehash", nil }
 
-		_, err := FetchPRsWithCache(nil, owner, repo, branch, 0, false, mockFetcherWithError, mockHashGetter, pathGetter)
+		_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", mockFetcherWithError, hashOf("c1"), alwaysAncestor, pathGetter)
 		assert.Error(t, err)
 		assert.EqualError(t, err, "simulated API error")
 	})
-}
\ No newline at end of file
+
+	t.Run("Incomplete records are returned but not stored", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+		var calls []fetchCall
+		complete := historyFetcher([]string{"c2", "c1"}, &calls)
+		incomplete := func(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string) ([]models.CommitRecord, error) {
+			records, _ := complete(ctx, client, owner, repo, branch, limit, stopAt)
+			return records, fmt.Errorf("%w: rate limited", ErrIncomplete)
+		}
+
+		records, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", incomplete, hashOf("c2"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c2", "c1"}, oidsOf(records))
+
+		_, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", complete, hashOf("c2"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Len(t, calls, 2)
+
+		records, err = FetchCommitsWithCache(context.Background(), nil, owner, repo, branch, 0, false, false, "", "", historyFetcher([]string{"c3", "c2", "c1"}, &calls), hashOf("c3"), alwaysAncestor, pathGetter)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"c3", "c2", "c1"}, oidsOf(records))
+		assert.Len(t, calls, 3)
+		assert.Equal(t, []string{"c2"}, calls[2].stopAt)
+	})
+
+	t.Run("Parallel scans keep every branch", func(t *testing.T) {
+		pathGetter, cleanup := setupTestCache(t, "")
+		defer cleanup()
+
+		var wg sync.WaitGroup
+		for i := range 8 {
+			wg.Add(1)
+			go func(b string) {
+				defer wg.Done()
+				fetcher := func(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string) ([]models.CommitRecord, error) {
+					return []models.CommitRecord{{Oid: branch + "-c1"}}, nil
+				}
+				_, err := FetchCommitsWithCache(context.Background(), nil, owner, repo, b, 0, false, false, "", "", fetcher, hashOf(b+"-c1"), alwaysAncestor, pathGetter)
+				assert.NoError(t, err)
+			}(fmt.Sprintf("branch-%d", i))
+		}
+		wg.Wait()
+
+		data, err := loadCache(pathGetter)
+		assert.NoError(t, err)
+		assert.Len(t, data.Repos["my-org/my-repo"].Branches, 8)
+		assert.Len(t, data.Repos["my-org/my-repo"].Commits, 8)
+
+		cachePath, _ := pathGetter()
+		leftovers, _ := filepath.Glob(cachePath + ".*.tmp")
+		assert.Empty(t, leftovers)
+	})
+}
@@ -0,0 +1,199 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/astein-peddi/git-tooling/output"
+	"github.com/spf13/cobra"
+)
+
+func SetupCacheCommand() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Inspect, prune and clear the local PR cache",
+	}
+
+	cacheCmd.AddCommand(setupCacheStatsCommand())
+	cacheCmd.AddCommand(setupCacheListCommand())
+	cacheCmd.AddCommand(setupCachePruneCommand())
+	cacheCmd.AddCommand(setupCacheClearCommand())
+
+	return cacheCmd
+}
+
+func setupCacheStatsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "Show where the cache lives and how much it holds",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := GetCachePath()
+			if err != nil {
+				return err
+			}
+
+			var size int64
+			if info, err := os.Stat(path); err == nil {
+				size = info.Size()
+			}
+
+			data, err := loadCache(GetCachePath)
+			if err != nil {
+				return fmt.Errorf("could not load cache: %w", err)
+			}
+
+			entries := data.entries()
+			repos := make(map[string]bool)
+			commits := 0
+			var oldest, newest time.Time
+			for _, entry := range entries {
+				repos[entry.Repo] = true
+				if entry.UpdatedAt.IsZero() {
+					continue
+				}
+				if oldest.IsZero() || entry.UpdatedAt.Before(oldest) {
+					oldest = entry.UpdatedAt
+				}
+				if entry.UpdatedAt.After(newest) {
+					newest = entry.UpdatedAt
+				}
+			}
+			for _, entry := range data.Repos {
+				commits += len(entry.Commits)
+			}
+
+			now := time.Now()
+			fmt.Printf("Path:             %s\n", path)
+			fmt.Printf("Size on disk:     %s\n", formatSize(size))
+			fmt.Printf("Repositories:     %d\n", len(repos))
+			fmt.Printf("Branch histories: %d\n", len(entries))
+			fmt.Printf("Commit records:   %d\n", commits)
+			if !oldest.IsZero() {
+				fmt.Printf("Oldest entry:     %s ago\n", formatAge(oldest, now))
+				fmt.Printf("Newest entry:     %s ago\n", formatAge(newest, now))
+			}
+
+			return nil
+		},
+	}
+}
+
+func setupCacheListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [owner/repo]",
+		Short: "List the cached branch histories per repo and branch",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := loadCache(GetCachePath)
+			if err != nil {
+				return fmt.Errorf("could not load cache: %w", err)
+			}
+
+			now := time.Now()
+			table := output.Table{Headers: []string{"Repo", "Branch", "Scope", "Head", "Commits", "Size", "Age"}}
+			for _, entry := range data.entries() {
+				if len(args) == 1 && !sameRepo(entry.Repo, args[0]) {
+					continue
+				}
+				table.Rows = append(table.Rows, []string{
+					entry.Repo,
+					entry.Branch,
+					entry.Scope,
+					shortHash(entry.Head),
+					strconv.Itoa(entry.Commits),
+					formatSize(int64(entry.Size)),
+					formatAge(entry.UpdatedAt, now),
+				})
+			}
+
+			if len(table.Rows) == 0 {
+				fmt.Println("No cached branch histories.")
+				return nil
+			}
+
+			return output.Render(os.Stdout, "table", table)
+		},
+	}
+}
+
+func setupCachePruneCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Remove cached branch histories that were not updated recently",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			olderThan, _ := cmd.Flags().GetString("older-than")
+			ttl, err := parseTTL(olderThan)
+			if err != nil {
+				return err
+			}
+
+			var removed int
+			err = updateCache(GetCachePath, func(data *prCacheData) {
+				removed = data.pruneOlderThan(time.Now().Add(-ttl))
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save cache: %w", err)
+			}
+
+			fmt.Printf("Removed %d branch histories older than %s.\n", removed, olderThan)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("older-than", "30d", "Remove histories last updated longer ago than this (e.g. 30d, 12h)")
+
+	return cmd
+}
+
+func setupCacheClearCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "clear [owner/repo]",
+		Short: "Delete the whole cache, or only what is cached for one repo",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				var deleted string
+				err := withCacheLock(GetCachePath, func(path string) error {
+					deleted = path
+					if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+						return fmt.Errorf("failed to delete cache: %w", err)
+					}
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("Deleted %s\n", deleted)
+
+				return nil
+			}
+
+			var removed int
+			err := updateCache(GetCachePath, func(data *prCacheData) {
+				removed = data.clearRepo(args[0])
+			})
+			if err != nil {
+				return fmt.Errorf("failed to save cache: %w", err)
+			}
+
+			fmt.Printf("Removed %d branch histories for %s.\n", removed, args[0])
+
+			return nil
+		},
+	}
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+
+	return hash
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// cacheMu serialises cache access between the goroutines of one process. The
+// lock file next to the cache does the same between processes.
+var cacheMu sync.Mutex
+
+// withCacheLock runs fn while holding both locks. fn receives the path of the
+// cache file.
+func withCacheLock(pathGetter PathGetter, fn func(path string) error) error {
+	path, err := pathGetter()
+	if err != nil {
+		return err
+	}
+
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	lock, err := os.OpenFile(path+".lock", os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open cache lock: %w", err)
+	}
+	defer lock.Close()
+
+	if err := lockFile(lock); err != nil {
+		return fmt.Errorf("could not lock cache: %w", err)
+	}
+	defer unlockFile(lock)
+
+	return fn(path)
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// entryInfo describes one cached branch history. Size counts the history and
+// the commit records it refers to, so records shared between branches are
+// counted for each of them.
+type entryInfo struct {
+	Repo      string
+	Scope     string
+	Branch    string
+	Head      string
+	Commits   int
+	Size      int
+	UpdatedAt time.Time
+}
+
+func (c *prCacheData) entries() []entryInfo {
+	var entries []entryInfo
+	for key, entry := range c.Repos {
+		repo, scope := splitRepoKey(key)
+		for branch, log := range entry.Branches {
+			entries = append(entries, entryInfo{
+				Repo:      repo,
+				Scope:     scope,
+				Branch:    branch,
+				Head:      log.Head,
+				Commits:   len(log.Oids),
+				Size:      entry.logSize(log),
+				UpdatedAt: log.UpdatedAt,
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Repo != entries[j].Repo {
+			return entries[i].Repo < entries[j].Repo
+		}
+		if entries[i].Branch != entries[j].Branch {
+			return entries[i].Branch < entries[j].Branch
+		}
+		return entries[i].Scope < entries[j].Scope
+	})
+
+	return entries
+}
+
+func (r *repoCache) logSize(log branchLog) int {
+	size := jsonSize(log)
+	for _, oid := range log.Oids {
+		size += jsonSize(r.Commits[oid])
+	}
+
+	return size
+}
+
+func jsonSize(value any) int {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+
+	return len(content)
+}
+
+// pruneOlderThan drops the branch histories last updated before cutoff,
+// together with the commit records only they referred to. It returns the
+// number of histories dropped.
+func (c *prCacheData) pruneOlderThan(cutoff time.Time) int {
+	removed := 0
+	for key, entry := range c.Repos {
+		for branch, log := range entry.Branches {
+			if log.UpdatedAt.Before(cutoff) {
+				delete(entry.Branches, branch)
+				removed++
+			}
+		}
+		entry.prune()
+		if len(entry.Branches) == 0 {
+			delete(c.Repos, key)
+		}
+	}
+
+	return removed
+}
+
+// clearRepo drops everything cached for an owner/repo, in every scope and in
+// both API and local mode. It returns the number of histories dropped.
+func (c *prCacheData) clearRepo(name string) int {
+	removed := 0
+	for key, entry := range c.Repos {
+		if repo, _ := splitRepoKey(key); sameRepo(repo, name) {
+			removed += len(entry.Branches)
+			delete(c.Repos, key)
+		}
+	}
+
+	return removed
+}
+
+// sameRepo matches a cached repo against an owner/repo given by the user,
+// whether it was scanned through the API or locally.
+func sameRepo(repo, name string) bool {
+	return strings.EqualFold(strings.TrimPrefix(repo, "local:"), name)
+}
+
+// splitRepoKey separates the repository from the scan scope in a key built
+// by repoCacheKey.
+func splitRepoKey(key string) (string, string) {
+	start := strings.Index(key, "[")
+	if start < 0 || !strings.HasSuffix(key, "]") {
+		return key, ""
+	}
+
+	return key[:start], key[start+1 : len(key)-1]
+}
+
+// parseTTL reads a --older-than value. On top of Go durations such as "12h",
+// it accepts whole days such as "30d".
+func parseTTL(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid --older-than '%s': use a number of days such as 30d, or a duration such as 12h", value)
+	}
+
+	return ttl, nil
+}
+
+func formatAge(updatedAt, now time.Time) string {
+	if updatedAt.IsZero() {
+		return "unknown"
+	}
+
+	age := now.Sub(updatedAt)
+	switch {
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
+	}
+}
+
+func formatSize(bytes int64) string {
+	switch {
+	case bytes < 1024:
+		return fmt.Sprintf("%d B", bytes)
+	case bytes < 1024*1024:
+		return fmt.Sprintf("%.1f KB", float64(bytes)/1024)
+	default:
+		return fmt.Sprintf("%.1f MB", float64(bytes)/(1024*1024))
+	}
+}
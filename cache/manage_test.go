@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func testCacheData(now time.Time) *prCacheData {
+	data := newCacheData()
+	api := data.repo("my-org/my-repo[squash,merge]")
+	api.add([]models.CommitRecord{{Oid: "c2"}, {Oid: "c1"}, {Oid: "c0"}})
+	api.Branches["dev"] = branchLog{Head: "c2", Oids: []string{"c2", "c1"}, UpdatedAt: now.Add(-time.Hour)}
+	api.Branches["main"] = branchLog{Head: "c0", Oids: []string{"c0"}, UpdatedAt: now.Add(-40 * 24 * time.Hour)}
+
+	local := data.repo("local:my-org/my-repo")
+	local.add([]models.CommitRecord{{Oid: "c1"}})
+	local.Branches["dev|limit=10"] = branchLog{Head: "c1", Oids: []string{"c1"}, UpdatedAt: now.Add(-2 * time.Hour)}
+
+	other := data.repo("other/repo")
+	other.add([]models.CommitRecord{{Oid: "d1"}})
+	other.Branches["main"] = branchLog{Head: "d1", Oids: []string{"d1"}, UpdatedAt: now.Add(-50 * 24 * time.Hour)}
+
+	return data
+}
+
+func TestEntries(t *testing.T) {
+	now := time.Now()
+	entries := testCacheData(now).entries()
+
+	assert.Len(t, entries, 4)
+	assert.Equal(t, "local:my-org/my-repo", entries[0].Repo)
+	assert.Equal(t, "dev|limit=10", entries[0].Branch)
+	assert.Equal(t, "my-org/my-repo", entries[1].Repo)
+	assert.Equal(t, "squash,merge", entries[1].Scope)
+	assert.Equal(t, "dev", entries[1].Branch)
+	assert.Equal(t, 2, entries[1].Commits)
+	assert.Greater(t, entries[1].Size, entries[2].Size)
+}
+
+func TestPruneOlderThan(t *testing.T) {
+	now := time.Now()
+	data := testCacheData(now)
+
+	removed := data.pruneOlderThan(now.Add(-30 * 24 * time.Hour))
+	assert.Equal(t, 2, removed)
+	assert.NotContains(t, data.Repos, "other/repo")
+
+	api := data.Repos["my-org/my-repo[squash,merge]"]
+	assert.Contains(t, api.Branches, "dev")
+	assert.NotContains(t, api.Branches, "main")
+	assert.NotContains(t, api.Commits, "c0")
+	assert.Contains(t, api.Commits, "c1")
+}
+
+func TestClearRepo(t *testing.T) {
+	data := testCacheData(time.Now())
+
+	assert.Equal(t, 3, data.clearRepo("My-Org/my-repo"))
+	assert.Len(t, data.Repos, 1)
+	assert.Contains(t, data.Repos, "other/repo")
+
+	assert.Equal(t, 0, data.clearRepo("unknown/repo"))
+}
+
+func TestParseTTL(t *testing.T) {
+	ttl, err := parseTTL("30d")
+	assert.NoError(t, err)
+	assert.Equal(t, 30*24*time.Hour, ttl)
+
+	ttl, err = parseTTL("12h")
+	assert.NoError(t, err)
+	assert.Equal(t, 12*time.Hour, ttl)
+
+	_, err = parseTTL("soon")
+	assert.Error(t, err)
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Now()
+	assert.Equal(t, "5m", formatAge(now.Add(-5*time.Minute), now))
+	assert.Equal(t, "3h", formatAge(now.Add(-3*time.Hour), now))
+	assert.Equal(t, "2d", formatAge(now.Add(-50*time.Hour), now))
+	assert.Equal(t, "unknown", formatAge(time.Time{}, now))
+}
@@ -2,6 +2,7 @@ package completion
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -10,13 +11,13 @@ import (
 	"github.com/spf13/cobra"
 )
 
-func FetchAllBranches() {
+func FetchAllBranches(ctx context.Context) {
 	if !utils.IsInsideGitRepository() {
 		return
 	}
 
-	cmd := exec.Command("git", "fetch", "--all", "--prune")
-	if err := cmd.Run(); err != nil {
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--all", "--prune")
+	if err := cmd.Run(); err != nil && ctx.Err() == nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not fetch branches: %v\n", err)
 	}
 }
@@ -1,18 +1,29 @@
 package loader
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/astein-peddi/git-tooling/theme"
+	"github.com/astein-peddi/git-tooling/utils"
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbletea"
 )
 
+// ErrCancelled is returned by Run when the user quit before the task ended.
+var ErrCancelled = errors.New("cancelled")
+
 type model struct {
-	spinner  spinner.Model
-	message  string
-	resultCh chan Result
-	result   Result
+	spinner    spinner.Model
+	message    string
+	resultCh   chan Result
+	progressCh chan Progress
+	board      progressBoard
+	result     Result
+	cancelled  bool
 }
 
 func waitForResultCmd(resultCh chan Result) tea.Cmd {
@@ -21,19 +32,27 @@ func waitForResultCmd(resultCh chan Result) tea.Cmd {
 	}
 }
 
-func InitialModel(message string, resultCh chan Result) model {
+func waitForProgressCmd(progressCh chan Progress) tea.Cmd {
+	return func() tea.Msg {
+		return <-progressCh
+	}
+}
+
+func InitialModel(message string, resultCh chan Result, progressCh chan Progress) model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = theme.DefaultTheme.Spinner
 	return model{
-		spinner:  s,
-		message:  message,
-		resultCh: resultCh,
+		spinner:    s,
+		message:    message,
+		resultCh:   resultCh,
+		progressCh: progressCh,
+		board:      newProgressBoard(),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, waitForResultCmd(m.resultCh))
+	return tea.Batch(m.spinner.Tick, waitForResultCmd(m.resultCh), waitForProgressCmd(m.progressCh))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -41,6 +60,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 			case "q", "ctrl+c":
+				m.cancelled = true
 				return m, tea.Quit
 		}
 
@@ -50,6 +70,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, cmd
 
+	case Progress:
+		m.board.update(msg)
+
+		return m, waitForProgressCmd(m.progressCh)
+
 	case Result:
 		m.result = msg
 
@@ -60,23 +85,101 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m model) View() string {
-	return fmt.Sprintf("\n %s %s...\n\n", m.spinner.View(), m.message)
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n %s %s...\n", m.spinner.View(), m.message)
+
+	width := 0
+	for _, label := range m.board.labels {
+		width = max(width, len(label))
+	}
+
+	for _, label := range m.board.labels {
+		fmt.Fprintf(&b, "   %-*s  %s\n", width, label, theme.DefaultTheme.MutedText.Render(m.board.progress[label].String()))
+	}
+	if budget := m.board.budget(); budget > 0 {
+		fmt.Fprintf(&b, "   %s\n", theme.DefaultTheme.MutedText.Render(fmt.Sprintf("API budget: %d points left", budget)))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
 }
 
-func Run(message string, task func() (any, error)) (any, error) {
+// Run shows a spinner while task runs, with the progress the task sends on
+// its progress channel listed per branch or project below it. Quitting the
+// spinner, or cancelling ctx, cancels the context given to task and waits for
+// it to return, so no request outlives the command. Run then returns
+// ErrCancelled.
+func Run(ctx context.Context, message string, task func(ctx context.Context, progress chan<- Progress) (any, error)) (any, error) {
+	if !utils.IsInteractive() {
+		return runPlain(ctx, message, task)
+	}
+
+	taskCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	resultCh := make(chan Result, 1)
+	progressCh := make(chan Progress, progressBuffer)
+	done := make(chan struct{})
 
 	go func() {
-		data, err := task()
+		defer close(done)
+		data, err := task(taskCtx, progressCh)
 		resultCh <- Result{Data: data, Err: err}
 	}()
 
-	p := tea.NewProgram(InitialModel(message, resultCh))
+	p := tea.NewProgram(InitialModel(message, resultCh, progressCh), tea.WithContext(ctx))
 	finalModel, err := p.Run()
+	cancel()
+	<-done
+
+	if ctx.Err() != nil {
+		return nil, ErrCancelled
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error running loader: %w", err)
 	}
 
-	result := finalModel.(model).result
-	return result.Data, result.Err
+	final, ok := finalModel.(model)
+	if !ok || final.cancelled || errors.Is(final.result.Err, context.Canceled) {
+		return nil, ErrCancelled
+	}
+
+	return final.result.Data, final.result.Err
+}
+
+// runPlain runs task without a spinner, for when stdin or stdout is not a
+// terminal. Progress goes to a status line on stderr when that is a
+// terminal, and is dropped otherwise so logs and redirected output stay
+// clean. Nothing waits for keys; cancelling ctx, as an interrupt does, stops
+// the task.
+func runPlain(ctx context.Context, message string, task func(ctx context.Context, progress chan<- Progress) (any, error)) (any, error) {
+	progressCh := make(chan Progress, progressBuffer)
+	drained := make(chan struct{})
+
+	go func() {
+		defer close(drained)
+
+		showStatus := utils.IsStderrTerminal()
+		board := newProgressBoard()
+		for progress := range progressCh {
+			if showStatus {
+				board.update(progress)
+				fmt.Fprintf(os.Stderr, "\r\033[K%s: %s", message, board.summary())
+			}
+		}
+		if showStatus && len(board.labels) > 0 {
+			fmt.Fprint(os.Stderr, "\r\033[K")
+		}
+	}()
+
+	data, err := task(ctx, progressCh)
+	close(progressCh)
+	<-drained
+
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return nil, ErrCancelled
+	}
+
+	return data, err
 }
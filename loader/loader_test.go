@@ -0,0 +1,35 @@
+package loader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunPlain(t *testing.T) {
+	t.Run("Returns the result of the task", func(t *testing.T) {
+		data, err := runPlain(context.Background(), "Scanning", func(ctx context.Context, progress chan<- Progress) (any, error) {
+			reporter := NewReporter(ctx, progress, "dev")
+			for i := 0; i < progressBuffer*2; i++ {
+				reporter.Page(1, 0)
+			}
+
+			return 42, nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 42, data)
+	})
+
+	t.Run("Returns ErrCancelled when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		_, err := runPlain(ctx, "Scanning", func(ctx context.Context, progress chan<- Progress) (any, error) {
+			cancel()
+			<-ctx.Done()
+
+			return nil, ctx.Err()
+		})
+		assert.ErrorIs(t, err, ErrCancelled)
+	})
+}
@@ -0,0 +1,174 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// progressBuffer is how many updates may wait for the spinner to draw them.
+// Updates carry running totals, so dropping one when the buffer is full only
+// skips an intermediate state. Final states are always delivered.
+const progressBuffer = 64
+
+// Progress is how far one part of a task has come, such as the scan of one
+// branch or the load of one project. Counters are running totals; Budget is
+// the remaining API budget, zero when it is not known.
+type Progress struct {
+	Label   string
+	Pages   int
+	Commits int
+	Items   int
+	Budget  int
+	Cached  bool
+	Done    bool
+}
+
+func (p Progress) String() string {
+	var parts []string
+	if p.Cached {
+		parts = append(parts, "from cache")
+	}
+	if p.Pages > 0 {
+		parts = append(parts, plural(p.Pages, "page"))
+	}
+	if p.Commits > 0 {
+		parts = append(parts, plural(p.Commits, "commit"))
+	}
+	if p.Items > 0 {
+		parts = append(parts, plural(p.Items, "item"))
+	}
+	if p.Done {
+		parts = append(parts, "done")
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// progressBoard keeps the latest progress of every label, in the order the
+// labels first reported.
+type progressBoard struct {
+	labels   []string
+	progress map[string]Progress
+}
+
+func newProgressBoard() progressBoard {
+	return progressBoard{progress: make(map[string]Progress)}
+}
+
+func (b *progressBoard) update(p Progress) {
+	if _, seen := b.progress[p.Label]; !seen {
+		b.labels = append(b.labels, p.Label)
+	}
+	b.progress[p.Label] = p
+}
+
+// budget is the API budget the most recent report that knew it carried.
+func (b progressBoard) budget() int {
+	budget := 0
+	for _, label := range b.labels {
+		if b.progress[label].Budget > 0 {
+			budget = b.progress[label].Budget
+		}
+	}
+
+	return budget
+}
+
+// summary puts every label on one line, for a terminal status line.
+func (b progressBoard) summary() string {
+	var parts []string
+	for _, label := range b.labels {
+		parts = append(parts, fmt.Sprintf("%s %s", label, b.progress[label]))
+	}
+	if budget := b.budget(); budget > 0 {
+		parts = append(parts, fmt.Sprintf("API budget %d", budget))
+	}
+
+	return strings.Join(parts, " | ")
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// Reporter keeps the progress of one labelled part of a task and sends it to
+// the loader after every change. Intermediate updates are dropped while the
+// loader is behind; cache hits and completion wait for it, until ctx is
+// cancelled. A nil Reporter reports nothing, so fetchers can be called
+// without a loader.
+type Reporter struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	ch       chan<- Progress
+	progress Progress
+}
+
+func NewReporter(ctx context.Context, ch chan<- Progress, label string) *Reporter {
+	if ch == nil {
+		return nil
+	}
+
+	return &Reporter{ctx: ctx, ch: ch, progress: Progress{Label: label}}
+}
+
+// Page records one page of results holding the given number of commits and
+// items.
+func (r *Reporter) Page(commits, items int) {
+	r.update(false, func(p *Progress) {
+		p.Pages++
+		p.Commits += commits
+		p.Items += items
+	})
+}
+
+// Budget records the API budget left after the last request.
+func (r *Reporter) Budget(remaining int) {
+	r.update(false, func(p *Progress) {
+		p.Budget = remaining
+	})
+}
+
+// Cached records that the part was served from the cache with the given
+// number of commits, without fetching anything.
+func (r *Reporter) Cached(commits int) {
+	r.update(true, func(p *Progress) {
+		p.Commits = commits
+		p.Cached = true
+	})
+}
+
+// Done marks the part finished.
+func (r *Reporter) Done() {
+	r.update(true, func(p *Progress) {
+		p.Done = true
+	})
+}
+
+func (r *Reporter) update(final bool, change func(p *Progress)) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	change(&r.progress)
+	if final {
+		select {
+		case r.ch <- r.progress:
+		case <-r.ctx.Done():
+		}
+		return
+	}
+
+	select {
+	case r.ch <- r.progress:
+	default:
+	}
+}
@@ -0,0 +1,102 @@
+package loader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReporter(t *testing.T) {
+	t.Run("Sends running totals after every change", func(t *testing.T) {
+		ch := make(chan Progress, progressBuffer)
+		reporter := NewReporter(context.Background(), ch, "dev")
+
+		reporter.Page(100, 0)
+		reporter.Page(42, 0)
+		reporter.Budget(4800)
+		reporter.Done()
+
+		var last Progress
+		for len(ch) > 0 {
+			last = <-ch
+		}
+		assert.Equal(t, Progress{Label: "dev", Pages: 2, Commits: 142, Budget: 4800, Done: true}, last)
+		assert.Equal(t, "2 pages · 142 commits · done", last.String())
+	})
+
+	t.Run("Reports cache hits", func(t *testing.T) {
+		ch := make(chan Progress, progressBuffer)
+		reporter := NewReporter(context.Background(), ch, "main")
+
+		reporter.Cached(1)
+		assert.Equal(t, "from cache · 1 commit", (<-ch).String())
+	})
+
+	t.Run("Never blocks the task", func(t *testing.T) {
+		ch := make(chan Progress, 1)
+		reporter := NewReporter(context.Background(), ch, "project #3")
+
+		reporter.Page(0, 100)
+		reporter.Page(0, 100)
+		assert.Equal(t, 100, (<-ch).Items)
+	})
+
+	t.Run("Waits for the loader to take the final state", func(t *testing.T) {
+		ch := make(chan Progress, 1)
+		reporter := NewReporter(context.Background(), ch, "dev")
+
+		reporter.Page(10, 0)
+		reporter.Page(10, 0)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			reporter.Done()
+		}()
+
+		assert.Equal(t, 10, (<-ch).Commits)
+		<-done
+		assert.Equal(t, Progress{Label: "dev", Pages: 2, Commits: 20, Done: true}, <-ch)
+	})
+
+	t.Run("Stops waiting once the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		ch := make(chan Progress, 1)
+		reporter := NewReporter(ctx, ch, "dev")
+
+		reporter.Page(10, 0)
+		cancel()
+		reporter.Done()
+		assert.Equal(t, 10, (<-ch).Commits)
+	})
+
+	t.Run("Does nothing without a loader", func(t *testing.T) {
+		reporter := NewReporter(context.Background(), nil, "dev")
+		assert.Nil(t, reporter)
+		reporter.Page(1, 0)
+		reporter.Done()
+	})
+}
+
+func TestModelShowsProgress(t *testing.T) {
+	m := InitialModel("Scanning branch histories", make(chan Result), make(chan Progress))
+
+	updated, _ := m.Update(Progress{Label: "dev", Pages: 3, Commits: 287, Budget: 4812})
+	updated, _ = updated.Update(Progress{Label: "main", Commits: 120, Cached: true, Done: true})
+
+	view := updated.View()
+	assert.Contains(t, view, "Scanning branch histories...")
+	assert.Contains(t, view, "dev ")
+	assert.Contains(t, view, "3 pages · 287 commits")
+	assert.Contains(t, view, "from cache · 120 commits · done")
+	assert.Contains(t, view, "API budget: 4812 points left")
+}
+
+func TestProgressBoardSummary(t *testing.T) {
+	board := newProgressBoard()
+	board.update(Progress{Label: "dev", Pages: 1, Commits: 100})
+	board.update(Progress{Label: "main", Commits: 12, Cached: true, Done: true})
+	board.update(Progress{Label: "dev", Pages: 2, Commits: 142, Budget: 4800})
+
+	assert.Equal(t, "dev 2 pages · 142 commits | main from cache · 12 commits · done | API budget 4800", board.summary())
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 
 	"github.com/astein-peddi/git-tooling/auth"
+	"github.com/astein-peddi/git-tooling/cache"
 	"github.com/astein-peddi/git-tooling/completion"
 	"github.com/astein-peddi/git-tooling/projects"
 	"github.com/astein-peddi/git-tooling/prs"
+	"github.com/astein-peddi/git-tooling/utils"
 	"github.com/spf13/cobra"
 )
 
@@ -22,8 +27,25 @@ func main() {
 	rootCmd.AddCommand(prs.SetupPrsCommand())
 	rootCmd.AddCommand(auth.SetupAuthCommand())
 	rootCmd.AddCommand(projects.SetupProjectsCommand())
+	rootCmd.AddCommand(cache.SetupCacheCommand())
+
+	// The first interrupt cancels the running command, so it can stop its
+	// requests and return. A second one kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		var exitErr *utils.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.Err != nil {
+				fmt.Fprintln(os.Stderr, exitErr.Err)
+			}
+			os.Exit(exitErr.Code)
+		}
 
-	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -0,0 +1,8 @@
+package models
+
+// BranchHistory is what a branch scan yields: the PRs found in its history and
+// the commits that could not be matched to any PR, such as direct pushes.
+type BranchHistory struct {
+	PRs     []PR     `json:"prs"`
+	Orphans []Commit `json:"orphans,omitempty"`
+}
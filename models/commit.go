@@ -0,0 +1,11 @@
+package models
+
+type Commit struct {
+	Oid          string `json:"oid"`
+	Subject      string `json:"subject"`
+	Author       string `json:"author,omitempty"`
+	Date         string `json:"date,omitempty"`
+	URL          string `json:"url,omitempty"`
+	RevertedBy   string `json:"revertedBy,omitempty"`
+	RevertedByPR int    `json:"revertedByPR,omitempty"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// CommitRecord is a scanned commit together with the PR it resolved to, if
+// any. The cache keeps one record per SHA, so branch histories can be rebuilt
+// from them without asking the API again.
+type CommitRecord struct {
+	Oid        string   `json:"oid"`
+	Message    string   `json:"message"`
+	Author     string   `json:"author,omitempty"`
+	Date       string   `json:"date,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	Parents    int      `json:"parents"`
+	ParentOids []string `json:"parentOids,omitempty"`
+	PR         *PR      `json:"pr,omitempty"`
+}
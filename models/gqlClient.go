@@ -1,5 +1,7 @@
 package models
 
+import "context"
+
 type GQLClient interface {
-	Query(string, any, map[string]any) error
-}
\ No newline at end of file
+	Query(context.Context, string, any, map[string]any) error
+}
@@ -1,6 +1,15 @@
 package models
 
 type PR struct {
-	Number int    `json:"number"`
-	Title  string `json:"title"`
-}
\ No newline at end of file
+	Number        int      `json:"number"`
+	Title         string   `json:"title"`
+	Author        string   `json:"author,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	MergedAt      string   `json:"mergedAt,omitempty"`
+	URL           string   `json:"url,omitempty"`
+	BaseRef       string   `json:"baseRef,omitempty"`
+	ClosingIssues []int    `json:"closingIssues,omitempty"`
+	Oid           string   `json:"oid,omitempty"`
+	RevertedBy    string   `json:"revertedBy,omitempty"`
+	RevertedByPR  int      `json:"revertedByPR,omitempty"`
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/cli/go-gh/v2/pkg/jq"
+	"github.com/spf13/cobra"
+)
+
+// JSONExporter writes results in the gh style: only the fields requested with
+// --json, optionally piped through a --jq expression.
+type JSONExporter struct {
+	fields []string
+	jq     string
+}
+
+// AddJSONFlags registers --json and --jq on a command. Passing --json without
+// a value lists the fields that can be selected.
+func AddJSONFlags(cmd *cobra.Command, persistent bool, fields []string) {
+	flags := cmd.Flags()
+	if persistent {
+		flags = cmd.PersistentFlags()
+	}
+	flags.String("json", "", "Output JSON with the specified comma-separated fields")
+	flags.String("jq", "", "Filter JSON output using a jq expression")
+
+	cmd.SetFlagErrorFunc(func(c *cobra.Command, err error) error {
+		if err.Error() == "flag needs an argument: --json" {
+			return fmt.Errorf("specify one or more comma-separated fields for `--json`:\n  %s", strings.Join(sortedFields(fields), "\n  "))
+		}
+
+		return err
+	})
+}
+
+// NewJSONExporter reads --json and --jq. It returns nil when --json was not
+// given.
+func NewJSONExporter(cmd *cobra.Command, fields []string) (*JSONExporter, error) {
+	value, _ := cmd.Flags().GetString("json")
+	expr, _ := cmd.Flags().GetString("jq")
+
+	if value == "" {
+		if expr != "" {
+			return nil, fmt.Errorf("cannot use `--jq` without specifying `--json`")
+		}
+		return nil, nil
+	}
+
+	known := make(map[string]bool)
+	for _, field := range fields {
+		known[field] = true
+	}
+
+	exporter := &JSONExporter{jq: expr}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !known[field] {
+			return nil, fmt.Errorf("unknown JSON field: %q\navailable fields:\n  %s", field, strings.Join(sortedFields(fields), "\n  "))
+		}
+		exporter.fields = append(exporter.fields, field)
+	}
+
+	return exporter, nil
+}
+
+// Select keeps only the requested fields of each record. Records are
+// converted through their JSON form, so the field names are the json tags of
+// the result structs. Fields a record does not have come out as null.
+func (e *JSONExporter) Select(records any) ([]map[string]any, error) {
+	raw, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal results to JSON: %w", err)
+	}
+
+	var decoded []map[string]any
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("failed to marshal results to JSON: %w", err)
+	}
+
+	selected := make([]map[string]any, 0, len(decoded))
+	for _, record := range decoded {
+		picked := make(map[string]any, len(e.fields))
+		for _, field := range e.fields {
+			picked[field] = record[field]
+		}
+		selected = append(selected, picked)
+	}
+
+	return selected, nil
+}
+
+func (e *JSONExporter) Write(w io.Writer, data any) error {
+	if e.jq != "" {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return fmt.Errorf("failed to marshal results to JSON: %w", err)
+		}
+
+		return jq.Evaluate(bytes.NewReader(raw), w, e.jq)
+	}
+
+	jsonData, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal results to JSON: %w", err)
+	}
+
+	_, err = fmt.Fprintln(w, string(jsonData))
+	return err
+}
+
+func sortedFields(fields []string) []string {
+	sorted := append([]string{}, fields...)
+	sort.Strings(sorted)
+
+	return sorted
+}
@@ -0,0 +1,80 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+type sampleRecord struct {
+	Number int      `json:"number"`
+	Title  string   `json:"title"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+func newJSONCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{Use: "test", RunE: func(*cobra.Command, []string) error { return nil }}
+	AddJSONFlags(cmd, false, []string{"number", "title", "labels"})
+	cmd.SetArgs(args)
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+
+	return cmd
+}
+
+func TestNewJSONExporter(t *testing.T) {
+	fields := []string{"number", "title", "labels"}
+
+	t.Run("Not requested", func(t *testing.T) {
+		cmd := newJSONCommand(t)
+		assert.NoError(t, cmd.Execute())
+
+		exporter, err := NewJSONExporter(cmd, fields)
+		assert.NoError(t, err)
+		assert.Nil(t, exporter)
+	})
+
+	t.Run("Unknown field", func(t *testing.T) {
+		cmd := newJSONCommand(t, "--json", "number,body")
+		assert.NoError(t, cmd.Execute())
+
+		_, err := NewJSONExporter(cmd, fields)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown JSON field: "body"`)
+	})
+
+	t.Run("jq without json", func(t *testing.T) {
+		cmd := newJSONCommand(t, "--jq", ".[]")
+		assert.NoError(t, cmd.Execute())
+
+		_, err := NewJSONExporter(cmd, fields)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing field list lists the fields", func(t *testing.T) {
+		cmd := newJSONCommand(t, "--json")
+		err := cmd.Execute()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "labels\n  number\n  title")
+	})
+}
+
+func TestJSONExporter(t *testing.T) {
+	records := []sampleRecord{{Number: 101, Title: "Feat", Labels: []string{"ui"}}, {Number: 102, Title: "Fix"}}
+
+	exporter := &JSONExporter{fields: []string{"number", "labels"}}
+	selected, err := exporter.Select(records)
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]any{
+		{"number": float64(101), "labels": []any{"ui"}},
+		{"number": float64(102), "labels": nil},
+	}, selected)
+
+	var buf bytes.Buffer
+	exporter.jq = `.[] | select(.labels == null) | .number`
+	assert.NoError(t, exporter.Write(&buf, selected))
+	assert.Equal(t, "102\n", buf.String())
+}
@@ -0,0 +1,143 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+)
+
+// Formats lists the built-in renderers accepted by --format.
+var Formats = []string{"table", "csv", "tsv", "markdown", "ndjson"}
+
+// Table is a flat view of a command's results. Headers and Rows feed the
+// column-based formats, while Records holds the structs that ndjson encodes,
+// one per line.
+type Table struct {
+	Headers []string
+	Rows    [][]string
+	Records []any
+}
+
+func ValidateFormat(format string) error {
+	for _, f := range Formats {
+		if f == format {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid --format '%s': must be one of %s", format, strings.Join(Formats, ", "))
+}
+
+func Render(w io.Writer, format string, table Table) error {
+	switch format {
+	case "table":
+		return renderText(w, table)
+	case "csv":
+		return renderDelimited(w, table, ',')
+	case "tsv":
+		return renderDelimited(w, table, '\t')
+	case "markdown":
+		return renderMarkdown(w, table)
+	case "ndjson":
+		return renderNDJSON(w, table)
+	}
+
+	return ValidateFormat(format)
+}
+
+// LoadTemplate parses a --template value. The value is read as a file when
+// one exists at that path, and is used as the template text otherwise.
+func LoadTemplate(value string) (*template.Template, error) {
+	content := value
+	if raw, err := os.ReadFile(value); err == nil {
+		content = string(raw)
+	}
+
+	tmpl, err := template.New("output").Funcs(template.FuncMap{"join": strings.Join}).Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func RenderTemplate(w io.Writer, value string, data any) error {
+	tmpl, err := LoadTemplate(value)
+	if err != nil {
+		return err
+	}
+
+	if err := tmpl.Execute(w, data); err != nil {
+		return fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return nil
+}
+
+func renderText(w io.Writer, table Table) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(table.Headers, "\t"))
+	for _, row := range table.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(cell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func renderDelimited(w io.Writer, table Table, delimiter rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = delimiter
+	if err := cw.Write(table.Headers); err != nil {
+		return err
+	}
+	if err := cw.WriteAll(table.Rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+func renderMarkdown(w io.Writer, table Table) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	var separators []string
+	for range table.Headers {
+		separators = append(separators, "---")
+	}
+
+	lines := []string{
+		"| " + strings.Join(table.Headers, " | ") + " |",
+		"| " + strings.Join(separators, " | ") + " |",
+	}
+	for _, row := range table.Rows {
+		cells := make([]string, len(row))
+		for i, cell := range row {
+			cells[i] = escape.Replace(cell)
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+	}
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func renderNDJSON(w io.Writer, table Table) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, record := range table.Records {
+		if err := encoder.Encode(record); err != nil {
+			return fmt.Errorf("failed to marshal results to JSON: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sampleTable() Table {
+	return Table{
+		Headers: []string{"Number", "Title"},
+		Rows:    [][]string{{"#101", "Feat: New API"}, {"#102", "Fix: a, b | c"}},
+		Records: []any{map[string]any{"number": 101}, map[string]any{"number": 102}},
+	}
+}
+
+func TestRender(t *testing.T) {
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{format: "table", expected: "Number  Title\n#101    Feat: New API\n#102    Fix: a, b | c\n"},
+		{format: "csv", expected: "Number,Title\n#101,Feat: New API\n#102,\"Fix: a, b | c\"\n"},
+		{format: "tsv", expected: "Number\tTitle\n#101\tFeat: New API\n#102\tFix: a, b | c\n"},
+		{format: "markdown", expected: "| Number | Title |\n| --- | --- |\n| #101 | Feat: New API |\n| #102 | Fix: a, b \\| c |\n"},
+		{format: "ndjson", expected: "{\"number\":101}\n{\"number\":102}\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			assert.NoError(t, Render(&buf, tc.format, sampleTable()))
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+
+	t.Run("Unknown format", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Render(&buf, "yaml", sampleTable())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "must be one of table, csv, tsv, markdown, ndjson")
+	})
+}
+
+func TestRenderTemplate(t *testing.T) {
+	data := []struct {
+		Number int
+		Labels []string
+	}{{Number: 101, Labels: []string{"bug", "ui"}}}
+
+	var buf bytes.Buffer
+	assert.NoError(t, RenderTemplate(&buf, `{{range .}}{{.Number}}: {{join .Labels ","}}{{"\n"}}{{end}}`, data))
+	assert.Equal(t, "101: bug,ui\n", buf.String())
+
+	assert.Error(t, RenderTemplate(&buf, "{{.Missing", data))
+}
@@ -1,12 +1,15 @@
 package projects
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/astein-peddi/git-tooling/loader"
 	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/output"
 	"github.com/astein-peddi/git-tooling/utils"
 	"github.com/charmbracelet/bubbletea"
 	"github.com/cli/shurcooL-graphql"
@@ -38,15 +41,15 @@ func SetupProjectsCommand() *cobra.Command {
 				return fmt.Errorf("failed to get repository details: %w", err)
 			}
 			if projectNumber == 0 {
-				task := func() (any, error) {
+				task := func(ctx context.Context, progress chan<- loader.Progress) (any, error) {
 					client, err := utils.GetGhGraphQLClient()
 					if err != nil {
 						return 0, err
 					}
-					return getLastProjectNumber(client, repoOwner, repoName)
+					return getLastProjectNumber(ctx, client, repoOwner, repoName)
 				}
 
-				result, err := loader.Run("Fetching latest project ID", task)
+				result, err := loader.Run(cmd.Context(), "Fetching latest project ID", task)
 				if err != nil {
 					return fmt.Errorf("failed to get last project number: %w", err)
 				}
@@ -60,8 +63,11 @@ func SetupProjectsCommand() *cobra.Command {
 
 	cmd.PersistentFlags().IntVar(&projectNumber, "id", 0, "Project number (defaults to last project)")
 	cmd.PersistentFlags().StringVar(&groupByField, "groupBy", "", "Group by a custom field (e.g., 'Priority')")
-	cmd.PersistentFlags().Bool("json", false, "Output results in JSON format")
+	output.AddJSONFlags(cmd, true, itemJSONFields)
 	cmd.PersistentFlags().Bool("unformatted", false, "Output results in unformatted mode")
+	cmd.PersistentFlags().String("format", "", "Output results as "+strings.Join(output.Formats, ", ")+" instead of the interactive table")
+	cmd.PersistentFlags().String("template", "", "Render results with a Go template (inline, or a path to a template file)")
+	cmd.MarkFlagsMutuallyExclusive("json", "unformatted", "format", "template")
 
 	listCmd := &cobra.Command{
 		Use:   "list",
@@ -69,36 +75,62 @@ func SetupProjectsCommand() *cobra.Command {
 	}
 
 		runListCommand := func(cmd *cobra.Command, filter ItemFilter) error {
-		jsonOutput, _ := cmd.Flags().GetBool("json")
 		unformattedOutput, _ := cmd.Flags().GetBool("unformatted")
+		format, _ := cmd.Flags().GetString("format")
+		templateText, _ := cmd.Flags().GetString("template")
 
-		task := func() (any, error) {
+		if format != "" {
+			if err := output.ValidateFormat(format); err != nil {
+				return err
+			}
+		}
+
+		exporter, err := output.NewJSONExporter(cmd, itemJSONFields)
+		if err != nil {
+			return err
+		}
+
+		if exporter == nil && format == "" && templateText == "" && !utils.IsInteractive() {
+			unformattedOutput = true
+		}
+
+		task := func(ctx context.Context, progress chan<- loader.Progress) (any, error) {
 			client, err := utils.GetGhGraphQLClient()
 			if err != nil {
 				return nil, err
 			}
 
-			allItems, projectTitle, err := fetchProjectData(client, repoOwner, repoName, projectNumber, groupByField)
+			reporter := loader.NewReporter(ctx, progress, fmt.Sprintf("project #%d", projectNumber))
+			allItems, projectTitle, err := fetchProjectData(ctx, client, repoOwner, repoName, projectNumber, groupByField, reporter)
 			if err != nil {
 				return nil, err
 			}
+			reporter.Done()
 
 			processedItems := processProjectItems(allItems, filter, groupByField)
 			return projectDataResult{items: processedItems, title: projectTitle}, nil
 		}
 
-		result, err := loader.Run("Fetching project items", task)
+		result, err := loader.Run(cmd.Context(), "Fetching project items", task)
 		if err != nil {
 			return err
 		}
 
 		data := result.(projectDataResult)
 
+		if templateText != "" {
+			return output.RenderTemplate(os.Stdout, templateText, itemRecords(repoOwner, repoName, data.items))
+		}
+
+		if format != "" {
+			return output.Render(os.Stdout, format, itemsTable(repoOwner, repoName, data.items, groupByField))
+		}
+
 		if unformattedOutput {
 			fmt.Printf("Project: %s\n\n", data.title)
 
 			for _, item := range data.items {
-				fmt.Printf("%d - %s\n", item.Content.PR.Number, item.Content.PR.Title)
+				fmt.Println(unformattedItemLine(item))
 			}
 
 			fmt.Println()
@@ -106,15 +138,13 @@ func SetupProjectsCommand() *cobra.Command {
 			return nil
 		}
 
-		if jsonOutput {
-			jsonData, err := json.MarshalIndent(data.items, "", "  ")
+		if exporter != nil {
+			selected, err := exporter.Select(itemRecords(repoOwner, repoName, data.items))
 			if err != nil {
-				return fmt.Errorf("failed to marshal results to JSON: %w", err)
+				return err
 			}
 
-			fmt.Println(string(jsonData))
-
-			return nil
+			return exporter.Write(os.Stdout, selected)
 		}
 
 		p := tea.NewProgram(initialModel(repoOwner, repoName, data.title, data.items, groupByField), tea.WithAltScreen())
@@ -197,7 +227,7 @@ func SetupProjectsCommand() *cobra.Command {
 			reviewerName, _ := cmd.Flags().GetString("name")
 			if reviewerName == "" {
 				var err error
-				reviewerName, err = utils.GetGhUsernameGraphQL()
+				reviewerName, err = utils.GetGhUsernameGraphQL(cmd.Context())
 				if err != nil {
 					return fmt.Errorf("could not determine current user: %w", err)
 				}
@@ -234,7 +264,7 @@ func SetupProjectsCommand() *cobra.Command {
 	return cmd
 }
 
-func getLastProjectNumber(client models.GQLClient, owner, repo string) (int, error) {
+func getLastProjectNumber(ctx context.Context, client models.GQLClient, owner, repo string) (int, error) {
 	var query struct {
 		Organization struct {
 			ProjectsV2 struct {
@@ -259,7 +289,7 @@ func getLastProjectNumber(client models.GQLClient, owner, repo string) (int, err
 		"repo":  graphql.String(repo),
 	}
 
-	if err := client.Query("LastProjectNumber", &query, variables); err != nil {
+	if err := client.Query(ctx, "LastProjectNumber", &query, variables); err != nil {
 		return 0, err
 	}
 
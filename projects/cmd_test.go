@@ -1,6 +1,7 @@
 package projects
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -38,14 +39,14 @@ func TestGetLastProjectNumber(t *testing.T) {
 		}{{Number: 5, CreatedAt: time.Now().Add(-time.Hour)}}
 
 		mockClient := &mockGQLClient{mockResponse: mockResponse}
-		num, err := getLastProjectNumber(mockClient, "my-org", "my-repo")
+		num, err := getLastProjectNumber(context.Background(), mockClient, "my-org", "my-repo")
 		assert.NoError(t, err)
 		assert.Equal(t, 10, num)
 	})
 
 	t.Run("Client returns an error", func(t *testing.T) {
 		mockClient := &mockGQLClient{mockErr: fmt.Errorf("API rate limit exceeded")}
-		_, err := getLastProjectNumber(mockClient, "my-org", "my-repo")
+		_, err := getLastProjectNumber(context.Background(), mockClient, "my-org", "my-repo")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "API rate limit exceeded")
 	})
@@ -71,7 +72,7 @@ func TestGetLastProjectNumber(t *testing.T) {
 		}{} 
 
 		mockClient := &mockGQLClient{mockResponse: mockResponse}
-		_, err := getLastProjectNumber(mockClient, "my-org", "my-repo")
+		_, err := getLastProjectNumber(context.Background(), mockClient, "my-org", "my-repo")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "no projects found")
 	})
@@ -0,0 +1,140 @@
+package projects
+
+import (
+	"fmt"
+
+	"github.com/astein-peddi/git-tooling/output"
+)
+
+// itemRecord is the documented JSON shape of a project item. It is kept apart
+// from the GraphQL structs in models.go so that query changes do not leak
+// into --json, --format ndjson or --template output.
+type itemRecord struct {
+	ID         string   `json:"id"`
+	Type       string   `json:"type"`
+	Number     int      `json:"number,omitempty"`
+	Title      string   `json:"title"`
+	URL        string   `json:"url,omitempty"`
+	MergedAt   string   `json:"mergedAt,omitempty"`
+	LinkedPRs  []int    `json:"linkedPRs,omitempty"`
+	Reviewers  []string `json:"reviewers,omitempty"`
+	FieldValue string   `json:"fieldValue,omitempty"`
+}
+
+var itemJSONFields = []string{"id", "type", "number", "title", "url", "mergedAt", "linkedPRs", "reviewers", "fieldValue"}
+
+func newItemRecord(owner, repo string, item ProjectItem) itemRecord {
+	itemType, number, title := itemSummary(item)
+	record := itemRecord{
+		ID:         fmt.Sprint(item.ID),
+		Type:       itemType,
+		Number:     number,
+		Title:      title,
+		URL:        itemURL(owner, repo, item),
+		FieldValue: getFieldValue(item),
+	}
+
+	var prs []PullRequestFragment
+	switch item.Content.Typename {
+		case "PullRequest":
+			prs = append(prs, item.Content.PR)
+			if item.Content.PR.MergedAt != nil {
+				record.MergedAt = *item.Content.PR.MergedAt
+			}
+
+		case "Issue":
+			prs = getLinkedPRs(item)
+			for _, pr := range prs {
+				record.LinkedPRs = append(record.LinkedPRs, pr.Number)
+			}
+	}
+
+	seen := make(map[string]bool)
+	for _, pr := range prs {
+		for _, rr := range pr.ReviewRequests.Nodes {
+			login := rr.RequestedReviewer.OnUser.Login
+			if login != "" && !seen[login] {
+				seen[login] = true
+				record.Reviewers = append(record.Reviewers, login)
+			}
+		}
+	}
+
+	return record
+}
+
+func itemRecords(owner, repo string, items []ProjectItem) []itemRecord {
+	records := make([]itemRecord, 0, len(items))
+	for _, item := range items {
+		records = append(records, newItemRecord(owner, repo, item))
+	}
+
+	return records
+}
+
+// itemSummary returns the type label, number and title of a project item.
+// Draft issues have no number and report 0.
+func itemSummary(item ProjectItem) (string, int, string) {
+	switch item.Content.Typename {
+		case "Issue":
+			return "Issue", item.Content.Issue.Number, item.Content.Issue.Title
+
+		case "PullRequest":
+			return "PR", item.Content.PR.Number, item.Content.PR.Title
+
+		case "DraftIssue":
+			return "Draft", 0, item.Content.DraftIssue.Title
+	}
+
+	return "", 0, ""
+}
+
+func itemNumberText(number int) string {
+	if number == 0 {
+		return "-"
+	}
+
+	return fmt.Sprintf("#%d", number)
+}
+
+func itemURL(owner, repo string, item ProjectItem) string {
+	switch item.Content.Typename {
+		case "Issue":
+			return fmt.Sprintf("https://github.com/%s/%s/issues/%d", owner, repo, item.Content.Issue.Number)
+
+		case "PullRequest":
+			return fmt.Sprintf("https://github.com/%s/%s/pull/%d", owner, repo, item.Content.PR.Number)
+	}
+
+	return ""
+}
+
+func unformattedItemLine(item ProjectItem) string {
+	itemType, number, title := itemSummary(item)
+	if number == 0 {
+		return fmt.Sprintf("%s - %s", itemType, title)
+	}
+
+	return fmt.Sprintf("%d - %s", number, title)
+}
+
+// itemsTable flattens project items into the rows rendered by --format.
+func itemsTable(owner, repo string, items []ProjectItem, groupBy string) output.Table {
+	table := output.Table{Headers: []string{"Type", "Number", "Title"}}
+	if groupBy != "" {
+		table.Headers = append(table.Headers, groupBy)
+	}
+	table.Headers = append(table.Headers, "URL")
+
+	for _, item := range items {
+		itemType, number, title := itemSummary(item)
+		row := []string{itemType, itemNumberText(number), title}
+		if groupBy != "" {
+			row = append(row, getFieldValue(item))
+		}
+		table.Rows = append(table.Rows, append(row, itemURL(owner, repo, item)))
+		table.Records = append(table.Records, newItemRecord(owner, repo, item))
+	}
+
+	return table
+}
@@ -0,0 +1,79 @@
+package projects
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnformattedItemLine(t *testing.T) {
+	issue := ProjectItem{}
+	issue.Content.Typename = "Issue"
+	issue.Content.Issue.Number = 12
+	issue.Content.Issue.Title = "Broken login"
+
+	draft := ProjectItem{}
+	draft.Content.Typename = "DraftIssue"
+	draft.Content.DraftIssue.Title = "Idea"
+
+	assert.Equal(t, "12 - Broken login", unformattedItemLine(issue))
+	assert.Equal(t, "Draft - Idea", unformattedItemLine(draft))
+}
+
+func TestItemsTable(t *testing.T) {
+	pr := ProjectItem{}
+	pr.Content.Typename = "PullRequest"
+	pr.Content.PR.Number = 7
+	pr.Content.PR.Title = "Add feature"
+	pr.FieldValueByName.Typename = "ProjectV2ItemFieldTextValue"
+	pr.FieldValueByName.TextValue.Text = "High"
+
+	draft := ProjectItem{}
+	draft.Content.Typename = "DraftIssue"
+	draft.Content.DraftIssue.Title = "Idea"
+
+	table := itemsTable("my-org", "my-repo", []ProjectItem{pr, draft}, "Priority")
+	assert.Equal(t, []string{"Type", "Number", "Title", "Priority", "URL"}, table.Headers)
+	assert.Equal(t, [][]string{
+		{"PR", "#7", "Add feature", "High", "https://github.com/my-org/my-repo/pull/7"},
+		{"Draft", "-", "Idea", "", ""},
+	}, table.Rows)
+	assert.Len(t, table.Records, 2)
+}
+
+func TestNewItemRecord(t *testing.T) {
+	mergedAt := "2024-05-01T10:00:00Z"
+	issue := ProjectItem{ID: "item-1"}
+	issue.Content.Typename = "Issue"
+	issue.Content.Issue.Number = 12
+	issue.Content.Issue.Title = "Broken login"
+	issue.Content.Issue.TimelineItems.Nodes = make([]struct {
+		ConnectedEvent struct {
+			Subject struct {
+				PullRequest PullRequestFragment `graphql:"... on PullRequest"`
+			}
+		} `graphql:"... on ConnectedEvent"`
+		CrossReferencedEvent struct {
+			Source struct {
+				PullRequest PullRequestFragment `graphql:"... on PullRequest"`
+			}
+		} `graphql:"... on CrossReferencedEvent"`
+		ReferencedEvent struct {
+			Subject struct {
+				PullRequest PullRequestFragment `graphql:"... on PullRequest"`
+			}
+		} `graphql:"... on ReferencedEvent"`
+	}, 1)
+	issue.Content.Issue.TimelineItems.Nodes[0].ConnectedEvent.Subject.PullRequest = newTestPR(7, "Fix login", &mergedAt, "jane", "john")
+
+	record := newItemRecord("my-org", "my-repo", issue)
+	assert.Equal(t, itemRecord{
+		ID:        "item-1",
+		Type:      "Issue",
+		Number:    12,
+		Title:     "Broken login",
+		URL:       "https://github.com/my-org/my-repo/issues/12",
+		LinkedPRs: []int{7},
+		Reviewers: []string{"jane", "john"},
+	}, record)
+}
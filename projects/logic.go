@@ -1,14 +1,20 @@
 package projects
 
 import (
+	"context"
 	"fmt"
 	"sort"
 
+	"github.com/astein-peddi/git-tooling/loader"
 	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/utils"
 	"github.com/cli/shurcooL-graphql"
 )
 
-func fetchProjectData(client models.GQLClient, owner, repo string, projectNumber int, groupByField string) ([]ProjectItem, string, error) {
+// fetchProjectData loads every item of a project, trying the organization
+// first and the repository second. Every page is reported to progress,
+// together with the API budget left.
+func fetchProjectData(ctx context.Context, client models.GQLClient, owner, repo string, projectNumber int, groupByField string, progress *loader.Reporter) ([]ProjectItem, string, error) {
 	var allItems []ProjectItem
 	var projectTitle string
 
@@ -31,7 +37,7 @@ func fetchProjectData(client models.GQLClient, owner, repo string, projectNumber
 		"fieldName": graphql.String(groupByField),
 	}
 
-	orgErr := client.Query("OrgProjectItems", &orgQuery, orgVariables)
+	orgErr := client.Query(ctx, "OrgProjectItems", &orgQuery, orgVariables)
 	if orgErr != nil {
 		return nil, "", fmt.Errorf("error querying organization project: %w", orgErr)
 	}
@@ -39,16 +45,18 @@ func fetchProjectData(client models.GQLClient, owner, repo string, projectNumber
 	if orgQuery.Organization.ProjectV2 != nil {
 		projectTitle = orgQuery.Organization.ProjectV2.Title
 		allItems = append(allItems, orgQuery.Organization.ProjectV2.Items.Nodes...)
+		reportPage(progress, client, len(orgQuery.Organization.ProjectV2.Items.Nodes))
 		pageInfo := orgQuery.Organization.ProjectV2.Items.PageInfo
 
 		for pageInfo.HasNextPage {
 			orgVariables["after"] = graphql.String(pageInfo.EndCursor)
 
-			if err := client.Query("OrgProjectItems", &orgQuery, orgVariables); err != nil {
+			if err := client.Query(ctx, "OrgProjectItems", &orgQuery, orgVariables); err != nil {
 				return nil, "", fmt.Errorf("failed during pagination of org project items: %w", err)
 			}
 
 			allItems = append(allItems, orgQuery.Organization.ProjectV2.Items.Nodes...)
+			reportPage(progress, client, len(orgQuery.Organization.ProjectV2.Items.Nodes))
 			pageInfo = orgQuery.Organization.ProjectV2.Items.PageInfo
 		}
 
@@ -75,7 +83,7 @@ func fetchProjectData(client models.GQLClient, owner, repo string, projectNumber
 		"fieldName": graphql.String(groupByField),
 	}
 
-	repoErr := client.Query("RepoProjectItems", &repoQuery, repoVariables)
+	repoErr := client.Query(ctx, "RepoProjectItems", &repoQuery, repoVariables)
 	if repoErr != nil {
 		return nil, "", fmt.Errorf("error querying repository project: %w", repoErr)
 	}
@@ -83,15 +91,17 @@ func fetchProjectData(client models.GQLClient, owner, repo string, projectNumber
 	if repoQuery.Repository.ProjectV2 != nil {
 		projectTitle = repoQuery.Repository.ProjectV2.Title
 		allItems = append(allItems, repoQuery.Repository.ProjectV2.Items.Nodes...)
+		reportPage(progress, client, len(repoQuery.Repository.ProjectV2.Items.Nodes))
 		pageInfo := repoQuery.Repository.ProjectV2.Items.PageInfo
 
 		for pageInfo.HasNextPage {
 			repoVariables["after"] = graphql.String(pageInfo.EndCursor)
-			if err := client.Query("RepoProjectItems", &repoQuery, repoVariables); err != nil {
+			if err := client.Query(ctx, "RepoProjectItems", &repoQuery, repoVariables); err != nil {
 				return nil, "", fmt.Errorf("failed during pagination of repo project items: %w", err)
 			}
 
 			allItems = append(allItems, repoQuery.Repository.ProjectV2.Items.Nodes...)
+			reportPage(progress, client, len(repoQuery.Repository.ProjectV2.Items.Nodes))
 			pageInfo = repoQuery.Repository.ProjectV2.Items.PageInfo
 		}
 
@@ -101,6 +111,13 @@ func fetchProjectData(client models.GQLClient, owner, repo string, projectNumber
 	return nil, "", fmt.Errorf("failed to find project #%d. Please check the project ID and your permissions", projectNumber)
 }
 
+func reportPage(progress *loader.Reporter, client models.GQLClient, items int) {
+	progress.Page(0, items)
+	if remaining, ok := utils.RemainingBudget(client); ok {
+		progress.Budget(remaining)
+	}
+}
+
 func processProjectItems(items []ProjectItem, filter ItemFilter, groupByField string) []ProjectItem {
 	var filteredItems []ProjectItem
 	for _, item := range items {
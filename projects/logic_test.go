@@ -1,6 +1,7 @@
 package projects
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -138,7 +139,7 @@ func TestFetchProjectData(t *testing.T) {
 		}
 
 		mockClient := &mockGQLClient{mockResponse: mockResponse}
-		items, title, err := fetchProjectData(mockClient, "my-org", "my-repo", 1, "")
+		items, title, err := fetchProjectData(context.Background(), mockClient, "my-org", "my-repo", 1, "", nil)
 		assert.NoError(t, err)
 		assert.Equal(t, "My Org Project", title)
 		assert.Len(t, items, 1)
@@ -146,7 +147,7 @@ func TestFetchProjectData(t *testing.T) {
 
 	t.Run("API returns an error on org query", func(t *testing.T) {
 		mockClient := &mockGQLClient{mockErr: fmt.Errorf("permission denied")}
-		_, _, err := fetchProjectData(mockClient, "my-org", "my-repo", 1, "")
+		_, _, err := fetchProjectData(context.Background(), mockClient, "my-org", "my-repo", 1, "", nil)
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "permission denied")
 	})
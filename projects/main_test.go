@@ -1,6 +1,7 @@
 package projects
 
 import (
+	"context"
 	"fmt"
 	"reflect"
 )
@@ -10,7 +11,7 @@ type mockGQLClient struct {
 	mockErr      error
 }
 
-func (m *mockGQLClient) Query(queryName string, response any, variables map[string]any) error {
+func (m *mockGQLClient) Query(ctx context.Context, queryName string, response any, variables map[string]any) error {
 	if m.mockErr != nil {
 		return m.mockErr
 	}
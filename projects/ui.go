@@ -45,16 +45,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					if itemIndex < 0 || itemIndex >= len(m.items) {
 						return m, nil
 					}
-					selectedItem := m.items[itemIndex]
-					var url string
-					switch selectedItem.Content.Typename {
-						case "Issue":
-							url = fmt.Sprintf("https://github.com/%s/%s/issues/%d", m.repoOwner, m.repoName, selectedItem.Content.Issue.Number)
-
-						case "PullRequest":
-							url = fmt.Sprintf("https://github.com/%s/%s/pull/%d", m.repoOwner, m.repoName, selectedItem.Content.PR.Number)
-					}
-
+					url := itemURL(m.repoOwner, m.repoName, m.items[itemIndex])
 					if url != "" {
 						return m, openURLCmd(url)
 					}
@@ -144,23 +135,9 @@ func setupTable(termWidth int, items []ProjectItem, groupBy string, dividers map
 		delete(dividers, k)
 	}
 	for _, item := range items {
-		var itemType, numberStr, title, groupValue string
-		switch item.Content.Typename {
-			case "Issue":
-				itemType = "Issue"
-				numberStr = fmt.Sprintf("#%d", item.Content.Issue.Number)
-				title = item.Content.Issue.Title
-
-			case "PullRequest":
-				itemType = "PR"
-				numberStr = fmt.Sprintf("#%d", item.Content.PR.Number)
-				title = item.Content.PR.Title
-
-			case "DraftIssue":
-				itemType = "Draft"
-				numberStr = "-"
-				title = item.Content.DraftIssue.Title
-		}
+		var groupValue string
+		itemType, number, title := itemSummary(item)
+		numberStr := itemNumberText(number)
 
 		if groupBy != "" {
 			groupValue = getFieldValue(item)
@@ -0,0 +1,100 @@
+package prs
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/utils"
+)
+
+// markBackports flags pending PRs whose change already landed in the target
+// branch under a different commit, e.g. a hotfix cherry-picked into main via
+// its own backport PR. Equivalence is decided by `git patch-id`, the same
+// check `git cherry` uses, so the commits must be available locally.
+func markBackports(pending []ComparedPR, targetPRs []models.PR) {
+	var oids []string
+	for _, pr := range pending {
+		oids = append(oids, pr.Oid)
+	}
+	for _, pr := range targetPRs {
+		oids = append(oids, pr.Oid)
+	}
+
+	patchIDs, ok := loadPatchIDs(oids)
+	if !ok {
+		return
+	}
+
+	targetByPatch := make(map[string]int)
+	for _, pr := range targetPRs {
+		if patchID, ok := patchIDs[pr.Oid]; ok {
+			targetByPatch[patchID] = pr.Number
+		}
+	}
+
+	for i, pr := range pending {
+		if pr.Status != statusPending {
+			continue
+		}
+		patchID, ok := patchIDs[pr.Oid]
+		if !ok {
+			continue
+		}
+		if backport, found := targetByPatch[patchID]; found {
+			pending[i].Status = statusBackported
+			pending[i].BackportedAs = backport
+		}
+	}
+}
+
+// markCommitBackports does the same for orphan commits, which are usually
+// cherry-picked straight onto the target branch rather than through a PR.
+func markCommitBackports(pending []ComparedCommit, target models.BranchHistory) {
+	var oids []string
+	for _, commit := range pending {
+		oids = append(oids, commit.Oid)
+	}
+	for _, commit := range target.Orphans {
+		oids = append(oids, commit.Oid)
+	}
+	for _, pr := range target.PRs {
+		oids = append(oids, pr.Oid)
+	}
+
+	patchIDs, ok := loadPatchIDs(oids)
+	if !ok {
+		return
+	}
+
+	targetByPatch := make(map[string]string)
+	for _, oid := range oids[len(pending):] {
+		if patchID, ok := patchIDs[oid]; ok {
+			targetByPatch[patchID] = oid
+		}
+	}
+
+	for i, commit := range pending {
+		if commit.Status != statusPending {
+			continue
+		}
+		patchID, ok := patchIDs[commit.Oid]
+		if !ok {
+			continue
+		}
+		if backport, found := targetByPatch[patchID]; found {
+			pending[i].Status = statusBackported
+			pending[i].BackportedAs = backport
+		}
+	}
+}
+
+func loadPatchIDs(oids []string) (map[string]string, bool) {
+	patchIDs, err := utils.GetPatchIDs(oids)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not detect backports: %v\n", err)
+		return nil, false
+	}
+
+	return patchIDs, true
+}
@@ -0,0 +1,175 @@
+package prs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/astein-peddi/git-tooling/utils"
+	"github.com/spf13/cobra"
+)
+
+const (
+	checkStatusClean   = "clean"
+	checkStatusPending = "pending"
+	checkStatusError   = "error"
+)
+
+// Exit codes of `prs check` besides 0 for a clean result. Errors keep the
+// generic exit code used by main.
+const (
+	exitError   = 1
+	exitPending = 2
+)
+
+type checkThresholds struct {
+	MaxAgeDays int      `json:"maxAgeDays,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+}
+
+type checkViolation struct {
+	Number  int      `json:"number"`
+	Title   string   `json:"title"`
+	Author  string   `json:"author,omitempty"`
+	URL     string   `json:"url,omitempty"`
+	AgeDays *int     `json:"ageDays,omitempty"`
+	Labels  []string `json:"labels,omitempty"`
+	Reasons []string `json:"reasons"`
+}
+
+type checkSummary struct {
+	Status     string           `json:"status"`
+	Source     string           `json:"source"`
+	Target     string           `json:"target"`
+	Pending    int              `json:"pending"`
+	Thresholds checkThresholds  `json:"thresholds"`
+	Violations []checkViolation `json:"violations"`
+	Snapshots  []branchSnapshot `json:"snapshots,omitempty"`
+	Error      string           `json:"error,omitempty"`
+}
+
+func setupCheckCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check <sourceRevision> <targetRevision>",
+		Short: "Fail when PRs in a source branch have waited too long to reach a target branch",
+		Long: `Compare two branches without any interactive output and print a JSON summary.
+
+Exit codes:
+  0  clean: no pending PR breaks a threshold
+  1  error: the comparison could not be made
+  2  pending: at least one pending PR breaks a threshold
+
+Without --max-age or --fail-on-label, every pending PR breaks the check.`,
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeBranches,
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source := args[0]
+			target := args[1]
+			maxAge, _ := cmd.Flags().GetInt("max-age")
+			labels, _ := cmd.Flags().GetStringSlice("fail-on-label")
+
+			summary := checkSummary{
+				Status:     checkStatusError,
+				Source:     source,
+				Target:     target,
+				Thresholds: checkThresholds{MaxAgeDays: maxAge, Labels: labels},
+				Violations: []checkViolation{},
+			}
+
+			compared, err := runCheckComparison(cmd, source, target)
+			if err != nil {
+				summary.Error = err.Error()
+				printCheckSummary(summary)
+				return &utils.ExitError{Code: exitError, Err: err}
+			}
+
+			summary.Snapshots = compared.snapshots
+			summary = evaluateCheck(summary, compared.prs, time.Now())
+			printCheckSummary(summary)
+
+			if summary.Status == checkStatusPending {
+				return &utils.ExitError{Code: exitPending}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Int("max-age", 0, "Fail when a pending PR was merged more than this many days ago (0=any age)")
+	cmd.Flags().StringSlice("fail-on-label", nil, "Fail when a pending PR carries one of these labels, regardless of age")
+
+	return cmd
+}
+
+func runCheckComparison(cmd *cobra.Command, source, target string) (comparison, error) {
+	if maxAge, _ := cmd.Flags().GetInt("max-age"); maxAge < 0 {
+		return comparison{}, fmt.Errorf("invalid --max-age %d: must not be negative", maxAge)
+	}
+
+	config, err := newScanConfig(cmd, source, target)
+	if err != nil {
+		return comparison{}, err
+	}
+
+	return config.compareBranches(cmd.Context(), nil, source, target, false, nil)
+}
+
+// evaluateCheck decides whether the pending PRs break the thresholds. With
+// no thresholds configured any pending PR does. When both are set, a PR
+// breaks the check by exceeding either one.
+func evaluateCheck(summary checkSummary, prs []ComparedPR, now time.Time) checkSummary {
+	summary.Status = checkStatusClean
+	summary.Violations = []checkViolation{}
+
+	failLabels := make(map[string]bool)
+	for _, label := range summary.Thresholds.Labels {
+		failLabels[strings.ToLower(label)] = true
+	}
+	noThresholds := summary.Thresholds.MaxAgeDays == 0 && len(failLabels) == 0
+
+	for _, pr := range prs {
+		if pr.Status != statusPending {
+			continue
+		}
+		summary.Pending++
+
+		violation := checkViolation{Number: pr.Number, Title: pr.Title, Author: pr.Author, URL: pr.URL, Labels: pr.Labels}
+		if mergedAt, err := time.Parse(time.RFC3339, pr.MergedAt); err == nil {
+			age := int(now.Sub(mergedAt).Hours() / 24)
+			violation.AgeDays = &age
+			if summary.Thresholds.MaxAgeDays > 0 && age > summary.Thresholds.MaxAgeDays {
+				violation.Reasons = append(violation.Reasons, fmt.Sprintf("pending for %d days (max %d)", age, summary.Thresholds.MaxAgeDays))
+			}
+		}
+		for _, label := range pr.Labels {
+			if failLabels[strings.ToLower(label)] {
+				violation.Reasons = append(violation.Reasons, fmt.Sprintf("has label '%s'", label))
+			}
+		}
+		if noThresholds {
+			violation.Reasons = append(violation.Reasons, "pending")
+		}
+
+		if len(violation.Reasons) > 0 {
+			summary.Violations = append(summary.Violations, violation)
+		}
+	}
+
+	if len(summary.Violations) > 0 {
+		summary.Status = checkStatusPending
+	}
+
+	return summary
+}
+
+func printCheckSummary(summary checkSummary) {
+	jsonData, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return
+	}
+
+	fmt.Println(string(jsonData))
+}
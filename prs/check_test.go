@@ -0,0 +1,44 @@
+package prs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvaluateCheck(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	prs := []ComparedPR{
+		{PR: models.PR{Number: 101, Title: "Old", MergedAt: "2024-03-01T12:00:00Z"}, Status: statusPending},
+		{PR: models.PR{Number: 102, Title: "Hotfix", MergedAt: "2024-03-30T12:00:00Z", Labels: []string{"HotFix"}}, Status: statusPending},
+		{PR: models.PR{Number: 103, Title: "Recent", MergedAt: "2024-03-29T12:00:00Z"}, Status: statusPending},
+		{PR: models.PR{Number: 104, Title: "Backported", MergedAt: "2024-01-01T12:00:00Z"}, Status: statusBackported},
+	}
+
+	t.Run("no thresholds fail on any pending PR", func(t *testing.T) {
+		summary := evaluateCheck(checkSummary{}, prs, now)
+		assert.Equal(t, checkStatusPending, summary.Status)
+		assert.Equal(t, 3, summary.Pending)
+		assert.Len(t, summary.Violations, 3)
+	})
+
+	t.Run("age and label thresholds", func(t *testing.T) {
+		summary := evaluateCheck(checkSummary{Thresholds: checkThresholds{MaxAgeDays: 7, Labels: []string{"hotfix"}}}, prs, now)
+		assert.Equal(t, checkStatusPending, summary.Status)
+		assert.Len(t, summary.Violations, 2)
+		assert.Equal(t, 101, summary.Violations[0].Number)
+		assert.Equal(t, 30, *summary.Violations[0].AgeDays)
+		assert.Equal(t, []string{"pending for 30 days (max 7)"}, summary.Violations[0].Reasons)
+		assert.Equal(t, 102, summary.Violations[1].Number)
+		assert.Equal(t, []string{"has label 'HotFix'"}, summary.Violations[1].Reasons)
+	})
+
+	t.Run("clean when nothing breaks a threshold", func(t *testing.T) {
+		summary := evaluateCheck(checkSummary{Thresholds: checkThresholds{MaxAgeDays: 60}}, prs, now)
+		assert.Equal(t, checkStatusClean, summary.Status)
+		assert.Equal(t, 3, summary.Pending)
+		assert.Empty(t, summary.Violations)
+	})
+}
@@ -1,154 +1,260 @@
 package prs
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"os"
 	"strings"
-	"sync"
 
-	"github.com/astein-peddi/git-tooling/cache"
 	"github.com/astein-peddi/git-tooling/loader"
-	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/output"
 	"github.com/astein-peddi/git-tooling/utils"
-	"github.com/charmbracelet/bubbletea"
 	"github.com/spf13/cobra"
 )
 
 func SetupPrsCommand() *cobra.Command {
-	var limit int
-
 	cmd := &cobra.Command{
-		Use:   "prs <sourceBranch> <targetBranch>",
-		Short: "List PRs in a source branch that are not in a target branch",
-		Args:  cobra.ExactArgs(2),
-		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-			if !utils.IsInsideGitRepository() {
-				return nil, cobra.ShellCompDirectiveNoFileComp
-			}
-			allBranches := utils.GetBranchNames()
-			var matches []string
-			for _, b := range allBranches {
-				if strings.HasPrefix(b, toComplete) {
-					matches = append(matches, b)
-				}
-			}
-			return matches, cobra.ShellCompDirectiveNoFileComp
-		},
+		Use:               "prs <sourceRevision> <targetRevision>",
+		Short:             "List PRs in a source branch that are not in a target branch",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeBranches,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			branchA := args[0]
 			branchB := args[1]
-			isLocal, _ := cmd.Flags().GetBool("local")
-			jsonOutput, _ := cmd.Flags().GetBool("json")
 			unformattedOutput, _ := cmd.Flags().GetBool("unformatted")
+			symmetric, _ := cmd.Flags().GetBool("symmetric")
+			showCommits, _ := cmd.Flags().GetBool("commits")
+			format, _ := cmd.Flags().GetString("format")
+			templateText, _ := cmd.Flags().GetString("template")
 
-			if isLocal {
-				return fmt.Errorf("local mode is not yet implemented")
+			if format != "" {
+				if err := output.ValidateFormat(format); err != nil {
+					return err
+				}
 			}
 
-			if !utils.DoesBranchExist(branchA, isLocal) {
-				return fmt.Errorf("branch '%s' does not exist", branchA)
+			exporter, err := output.NewJSONExporter(cmd, prJSONFields)
+			if err != nil {
+				return err
 			}
-			if !utils.DoesBranchExist(branchB, isLocal) {
-				return fmt.Errorf("branch '%s' does not exist", branchB)
+
+			// The interactive table needs a terminal; piped output gets the
+			// plain lines instead.
+			if exporter == nil && format == "" && templateText == "" && !utils.IsInteractive() {
+				unformattedOutput = true
 			}
 
-			owner, repo, err := utils.GetRepoOwnerAndName()
+			config, err := newScanConfig(cmd, branchA, branchB)
 			if err != nil {
 				return err
 			}
 
-			task := func() (any, error) {
-				client, err := utils.GetGhGraphQLClient()
-				if err != nil {
-					return nil, err
-				}
+			if templateText == "" && format == "" && !unformattedOutput && exporter == nil {
+				return streamComparison(cmd.Context(), config, branchA, branchB, symmetric, showCommits)
+			}
 
-				var wg sync.WaitGroup
-				resultsChan := make(chan branchScanResult, 2)
+			task := func(ctx context.Context, progress chan<- loader.Progress) (any, error) {
+				return config.compareBranches(ctx, progress, branchA, branchB, symmetric, nil)
+			}
+
+			result, err := loader.Run(cmd.Context(), "Scanning branch histories", task)
+			if err != nil {
+				return err
+			}
 
-				for _, branch := range []string{branchA, branchB} {
-					wg.Add(1)
-					go func(b string) {
-						defer wg.Done()
+			compared := result.(comparison)
+			finalPRs := compared.prs
+			var commits []ComparedCommit
+			if showCommits {
+				commits = compared.commits
+			}
 
-						prs, err := cache.FetchPRsWithCache(
-							client, owner, repo, b, limit, isLocal,
-							FetchPRsForBranch, 
-							cache.GetBranchHeadHash, 
-							cache.GetCachePath,      
-						)
+			printSnapshot(compared.snapshots)
 
-						resultsChan <- branchScanResult{branchName: b, prs: prs, err: err}
-					}(branch)
-				}
+			if templateText != "" {
+				return output.RenderTemplate(os.Stdout, templateText, resultData(finalPRs, commits, showCommits))
+			}
 
-				wg.Wait()
-				close(resultsChan)
-
-				var branchAPrs, branchBPrs []models.PR
-				for result := range resultsChan {
-					if result.err != nil {
-						return nil, result.err
-					}
-					if result.branchName == branchA {
-						branchAPrs = result.prs
-					} else {
-						branchBPrs = result.prs
-					}
-				}
+			if format != "" {
+				return output.Render(os.Stdout, format, resultTable(finalPRs, commits, symmetric))
+			}
 
-				branchBSet := make(map[int]bool)
-				for _, pr := range branchBPrs {
-					branchBSet[pr.Number] = true
+			if unformattedOutput {
+				if !symmetric {
+					printUnformattedSection(finalPRs, commits, showCommits, directionForward)
+
+					return nil
 				}
 
-				var finalResults []models.PR
-				for _, prA := range branchAPrs {
-					if !branchBSet[prA.Number] {
-						finalResults = append(finalResults, prA)
-					}
+				for _, section := range []struct{ direction, from, to string }{
+					{directionForward, branchA, branchB},
+					{directionReverse, branchB, branchA},
+				} {
+					fmt.Printf("In '%s' but not in '%s':\n", section.from, section.to)
+					printUnformattedSection(finalPRs, commits, showCommits, section.direction)
 				}
 
-				return finalResults, nil
+				return nil
 			}
 
-			result, err := loader.Run("Scanning branch histories", task)
-			if err != nil {
-				return err
-			}
+			return writeJSON(exporter, finalPRs, commits, showCommits)
+		},
+	}
 
-			finalPRs := result.([]models.PR)
+	cmd.PersistentFlags().BoolP("local", "l", false, "Compare local branches using only local git history (no API calls)")
+	cmd.PersistentFlags().Int("limit", 0, "Max number of commits to scan per branch (0=all)")
+	cmd.PersistentFlags().Bool("full-history", false, "Scan entire branch histories instead of only the commits since the merge base")
+	cmd.PersistentFlags().Bool("detect-backports", false, "Use patch-id equivalence to mark PRs already present in the target branch under another commit")
+	cmd.PersistentFlags().Bool("include-reverted", false, "Keep PRs that were reverted later in the same branch, marked as reverted")
+	cmd.PersistentFlags().Bool("associated", false, "Resolve PRs from GitHub's associatedPullRequests for each commit, falling back to commit messages")
+	cmd.PersistentFlags().StringSlice("extractors", nil, "PR reference extractors to apply: squash, merge, rebase (defaults to git config peddi-tooling.extractors, then squash,merge)")
+	cmd.PersistentFlags().StringArray("pattern", nil, "Custom regex whose first capture group is the PR number (repeatable, adds to git config peddi-tooling.pattern)")
+	cmd.PersistentFlags().StringSlice("author", nil, "Only keep PRs and commits by these authors (GitHub login, or git author name with --local)")
+	cmd.PersistentFlags().StringSlice("label", nil, "Only keep PRs with at least one of these labels")
+	cmd.PersistentFlags().String("since", "", "Only keep PRs merged on or after this date (YYYY-MM-DD or RFC 3339)")
+	cmd.PersistentFlags().String("until", "", "Only keep PRs merged on or before this date (YYYY-MM-DD or RFC 3339)")
+	cmd.PersistentFlags().StringArray("path", nil, "Only scan commits that touch this path (repeatable)")
+	cmd.PersistentFlags().Bool("fetch", false, "Run git fetch when local remote-tracking refs are behind GitHub, before comparing")
+	cmd.Flags().Bool("symmetric", false, "Also report PRs in the target branch that are missing from the source branch")
+	cmd.Flags().Bool("commits", false, "Also list commits that match no PR, such as direct pushes")
+	output.AddJSONFlags(cmd, false, prJSONFields)
+	cmd.Flags().Bool("unformatted", false, "Output results in unformatted mode")
+	cmd.Flags().String("format", "", "Output results as "+strings.Join(output.Formats, ", ")+" instead of the interactive table")
+	cmd.Flags().String("template", "", "Render results with a Go template (inline, or a path to a template file)")
+	cmd.MarkFlagsMutuallyExclusive("json", "unformatted", "format", "template")
 
-			if unformattedOutput {
-				for _, pr := range finalPRs {
-					fmt.Printf("%d - %s\n", pr.Number, pr.Title)
-				}
+	cmd.AddCommand(setupReleaseNotesCommand())
+	cmd.AddCommand(setupMatrixCommand())
+	cmd.AddCommand(setupCheckCommand())
 
-				fmt.Println()
+	return cmd
+}
 
-				return nil
-			}
+func completeBranches(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if !utils.IsInsideGitRepository() {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	allBranches := utils.GetBranchNames()
+	var matches []string
+	for _, b := range allBranches {
+		if strings.HasPrefix(b, toComplete) {
+			matches = append(matches, b)
+		}
+	}
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
 
-			if jsonOutput {
-				jsonData, err := json.MarshalIndent(finalPRs, "", "  ")
-				if err != nil {
-					return fmt.Errorf("failed to marshal results to JSON: %w", err)
-				}
-				fmt.Println(string(jsonData))
-				return nil
+func printUnformattedSection(prs []ComparedPR, commits []ComparedCommit, showCommits bool, direction string) {
+	for _, pr := range prs {
+		if pr.Direction == direction {
+			fmt.Println(unformattedLine(pr))
+		}
+	}
+
+	if showCommits {
+		fmt.Println("Commits without a PR:")
+		for _, commit := range commits {
+			if commit.Direction == direction {
+				fmt.Println(unformattedCommitLine(commit))
 			}
+		}
+	}
+
+	fmt.Println()
+}
 
-			p := tea.NewProgram(initialModel(branchA, branchB, finalPRs), tea.WithAltScreen())
-			_, err = p.Run()
+func unformattedLine(pr ComparedPR) string {
+	line := fmt.Sprintf("%d - %s", pr.Number, pr.Title)
 
-			return err
-		},
+	var details []string
+	if pr.Author != "" {
+		details = append(details, "by "+pr.Author)
+	}
+	if pr.MergedAt != "" {
+		details = append(details, "merged "+shortDate(pr.MergedAt))
+	}
+	if pr.BaseRef != "" {
+		details = append(details, "into "+pr.BaseRef)
+	}
+	if len(pr.Labels) > 0 {
+		details = append(details, "labels: "+strings.Join(pr.Labels, ", "))
+	}
+	if len(pr.ClosingIssues) > 0 {
+		details = append(details, "closes "+issueList(pr.ClosingIssues))
+	}
+	if pr.Status == statusBackported {
+		details = append(details, fmt.Sprintf("present via backport #%d", pr.BackportedAs))
+	}
+	if pr.Status == statusReverted {
+		details = append(details, "reverted by "+revertReference(pr.RevertedBy, pr.RevertedByPR))
 	}
 
-	cmd.Flags().BoolP("local", "l", false, "Compare local branches")
-	cmd.Flags().IntVar(&limit, "limit", 0, "Max number of commits to scan per branch (0=all)")
-	cmd.Flags().Bool("json", false, "Output results in JSON format")
-	cmd.Flags().Bool("unformatted", false, "Output results in unformatted mode")
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, "; ") + ")"
+	}
+	if pr.URL != "" {
+		line += " " + pr.URL
+	}
 
-	return cmd
+	return line
+}
+
+func unformattedCommitLine(commit ComparedCommit) string {
+	line := fmt.Sprintf("%s - %s", shortOid(commit.Oid), commit.Subject)
+
+	var details []string
+	if commit.Author != "" {
+		details = append(details, "by "+commit.Author)
+	}
+	if commit.Date != "" {
+		details = append(details, "committed "+shortDate(commit.Date))
+	}
+	if commit.Status == statusBackported {
+		details = append(details, "present via backport "+shortOid(commit.BackportedAs))
+	}
+	if commit.Status == statusReverted {
+		details = append(details, "reverted by "+revertReference(commit.RevertedBy, commit.RevertedByPR))
+	}
+
+	if len(details) > 0 {
+		line += " (" + strings.Join(details, "; ") + ")"
+	}
+	if commit.URL != "" {
+		line += " " + commit.URL
+	}
+
+	return line
+}
+
+func revertReference(oid string, prNumber int) string {
+	if prNumber != 0 {
+		return fmt.Sprintf("#%d", prNumber)
+	}
+
+	return shortOid(oid)
+}
+
+func shortOid(oid string) string {
+	if len(oid) > 7 {
+		return oid[:7]
+	}
+
+	return oid
+}
+
+func shortDate(timestamp string) string {
+	if len(timestamp) >= len("2006-01-02") {
+		return timestamp[:len("2006-01-02")]
+	}
+
+	return timestamp
+}
+
+func issueList(numbers []int) string {
+	var refs []string
+	for _, number := range numbers {
+		refs = append(refs, fmt.Sprintf("#%d", number))
+	}
+
+	return strings.Join(refs, " ")
 }
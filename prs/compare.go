@@ -0,0 +1,354 @@
+package prs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/astein-peddi/git-tooling/cache"
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/utils"
+	"github.com/spf13/cobra"
+)
+
+type scanConfig struct {
+	owner           string
+	repo            string
+	isLocal         bool
+	limit           int
+	fullHistory     bool
+	detectBackports bool
+	includeReverted bool
+	fetch           bool
+	scope           string
+	filter          historyFilter
+	opts            ScanOptions
+}
+
+func newScanConfig(cmd *cobra.Command, branches ...string) (scanConfig, error) {
+	isLocal, _ := cmd.Flags().GetBool("local")
+	associated, _ := cmd.Flags().GetBool("associated")
+	fullHistory, _ := cmd.Flags().GetBool("full-history")
+	detectBackports, _ := cmd.Flags().GetBool("detect-backports")
+	includeReverted, _ := cmd.Flags().GetBool("include-reverted")
+	fetch, _ := cmd.Flags().GetBool("fetch")
+	limit, _ := cmd.Flags().GetInt("limit")
+	extractorNames, _ := cmd.Flags().GetStringSlice("extractors")
+	patterns, _ := cmd.Flags().GetStringArray("pattern")
+	authors, _ := cmd.Flags().GetStringSlice("author")
+	labels, _ := cmd.Flags().GetStringSlice("label")
+	since, _ := cmd.Flags().GetString("since")
+	until, _ := cmd.Flags().GetString("until")
+	paths, _ := cmd.Flags().GetStringArray("path")
+
+	for _, branch := range branches {
+		if !utils.DoesRevisionExist(cmd.Context(), branch, isLocal) {
+			return scanConfig{}, fmt.Errorf("revision '%s' does not exist", branch)
+		}
+	}
+
+	owner, repo, err := utils.GetRepoOwnerAndName()
+	if err != nil {
+		if !isLocal {
+			return scanConfig{}, err
+		}
+		owner, repo = "local", utils.GetRepoDirName()
+	}
+
+	if isLocal && associated {
+		return scanConfig{}, fmt.Errorf("--associated resolves PRs through the GitHub API and cannot be combined with --local")
+	}
+
+	if isLocal && len(labels) > 0 {
+		return scanConfig{}, fmt.Errorf("--label needs PR details from the GitHub API and cannot be combined with --local")
+	}
+
+	filter, err := newHistoryFilter(authors, labels, since, until, paths)
+	if err != nil {
+		return scanConfig{}, err
+	}
+
+	extractors, scope, err := resolveExtractors(extractorNames, patterns)
+	if err != nil {
+		return scanConfig{}, err
+	}
+	if associated {
+		scope = "associated;" + scope
+	}
+	if filterScope := filter.scope(); filterScope != "" {
+		scope += ";" + filterScope
+	}
+
+	return scanConfig{
+		owner:           owner,
+		repo:            repo,
+		isLocal:         isLocal,
+		limit:           limit,
+		fullHistory:     fullHistory,
+		detectBackports: detectBackports,
+		includeReverted: includeReverted,
+		fetch:           fetch,
+		scope:           scope,
+		filter:          filter,
+		opts:            ScanOptions{Associated: associated, Extractors: extractors, Paths: paths},
+	}, nil
+}
+
+func (c scanConfig) client() (models.GQLClient, error) {
+	if c.isLocal {
+		return nil, nil
+	}
+
+	return utils.GetGhGraphQLClient()
+}
+
+// scanBranches fetches the history of every branch in parallel, going through
+// the cache. Each branch is scanned at its snapshot head, so all pages come
+// from the same point in time. When since is set, each history is only
+// scanned back to that commit. Only --path narrows what is scanned; the
+// other filters are left to the caller. When partial is set, it receives each branch's
+// history as it grows, after every page and once the branch is complete; the
+// pages carry no PR details yet.
+func (c scanConfig) scanBranches(ctx context.Context, progress chan<- loader.Progress, client models.GQLClient, snapshots []branchSnapshot, since string, partial func(branch string, history models.BranchHistory)) (map[string]models.BranchHistory, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	resultsChan := make(chan branchScanResult, len(snapshots))
+
+	for _, snapshot := range snapshots {
+		wg.Add(1)
+		go func(s branchSnapshot) {
+			defer wg.Done()
+
+			reporter := loader.NewReporter(ctx, progress, s.Branch)
+			fetched := false
+			fetcher := func(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string) ([]models.CommitRecord, error) {
+				fetched = true
+				opts := c.opts
+				opts.StopAt = stopAt
+				opts.Revision = s.Head
+				opts.Progress = reporter
+				if partial != nil {
+					opts.OnPage = func(commits []Commit) {
+						prs, orphans := extractPRsFromCommits(commits, c.opts.Extractors)
+						partial(s.Branch, models.BranchHistory{PRs: prs, Orphans: orphans})
+					}
+				}
+				if c.isLocal {
+					return FetchCommitsForLocalBranch(ctx, branch, limit, opts)
+				}
+				return FetchCommitsForBranch(ctx, client, owner, repo, branch, limit, opts)
+			}
+			snapshotHead := func(branchRef string) (string, error) {
+				return s.Head, nil
+			}
+
+			records, err := cache.FetchCommitsWithCache(
+				ctx, client, c.owner, c.repo, s.Branch, c.limit, c.isLocal, len(c.opts.Paths) > 0, c.scope, since,
+				fetcher,
+				snapshotHead,
+				cache.IsAncestor,
+				cache.GetCachePath,
+			)
+			if err != nil {
+				cancel()
+			} else if !fetched {
+				reporter.Cached(len(records))
+			}
+			reporter.Done()
+			history := historyFromRecords(records, c.opts.Extractors)
+			if err == nil && partial != nil {
+				partial(s.Branch, history)
+			}
+
+			resultsChan <- branchScanResult{branchName: s.Branch, history: history, err: err}
+		}(snapshot)
+	}
+
+	wg.Wait()
+	close(resultsChan)
+
+	// A failing branch cancels the others, so their cancellation errors are
+	// only reported when no branch failed for another reason.
+	var firstErr error
+	results := make(map[string]models.BranchHistory)
+	for result := range resultsChan {
+		if result.err != nil {
+			if firstErr == nil || errors.Is(firstErr, context.Canceled) {
+				firstErr = result.err
+			}
+			continue
+		}
+		results[result.branchName] = result.history
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return results, nil
+}
+
+// compareBranches returns the PRs and orphan commits in branchA that are not
+// in branchB. With symmetric set, those in branchB that are not in branchA are
+// appended, marked with the reverse direction. When partial is set, it
+// receives a preview of the comparison every time either history grows, once
+// both branches have reported; previews skip backport detection.
+func (c scanConfig) compareBranches(ctx context.Context, progress chan<- loader.Progress, branchA, branchB string, symmetric bool, partial func(comparison)) (comparison, error) {
+	client, err := c.client()
+	if err != nil {
+		return comparison{}, err
+	}
+
+	snapshots, err := c.resolveSnapshots(ctx, client, []string{branchA, branchB})
+	if err != nil {
+		return comparison{}, err
+	}
+
+	var since string
+	if !c.fullHistory {
+		if base, err := findMergeBase(ctx, c.owner, c.repo, branchA, branchB, c.isLocal); err == nil {
+			since = base
+		}
+	}
+
+	var onHistory func(branch string, history models.BranchHistory)
+	if partial != nil {
+		var mu sync.Mutex
+		histories := make(map[string]models.BranchHistory)
+		preview := c
+		preview.detectBackports = false
+		var unfiltered bool
+		preview.filter, unfiltered = c.filter.withoutDetails(c.isLocal)
+
+		onHistory = func(branch string, history models.BranchHistory) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			histories[branch] = history
+			if len(histories) < 2 {
+				return
+			}
+			result := preview.compare(histories[branchA], histories[branchB], symmetric)
+			result.snapshots = snapshots
+			result.unfiltered = unfiltered
+			partial(result)
+		}
+	}
+
+	results, err := c.scanBranches(ctx, progress, client, snapshots, since, onHistory)
+	if err != nil {
+		return comparison{}, err
+	}
+
+	result := c.compare(results[branchA], results[branchB], symmetric)
+	result.snapshots = snapshots
+
+	return result, nil
+}
+
+func (c scanConfig) compare(a, b models.BranchHistory, symmetric bool) comparison {
+	result := c.diffHistories(a, b, directionForward)
+	if symmetric {
+		reverse := c.diffHistories(b, a, directionReverse)
+		result.prs = append(result.prs, reverse.prs...)
+		result.commits = append(result.commits, reverse.commits...)
+	}
+
+	if !c.includeReverted {
+		result = result.withoutReverted()
+	}
+
+	return c.filter.apply(result)
+}
+
+func (c scanConfig) diffHistories(source, target models.BranchHistory, direction string) comparison {
+	prs := diffPRs(source.PRs, target.PRs, direction)
+	commits := diffCommits(source.Orphans, target, direction)
+	if c.detectBackports {
+		markBackports(prs, target.PRs)
+		markCommitBackports(commits, target)
+	}
+
+	return comparison{prs: prs, commits: commits}
+}
+
+func diffPRs(source, target []models.PR, direction string) []ComparedPR {
+	targetSet := make(map[int]bool)
+	for _, pr := range target {
+		targetSet[pr.Number] = true
+	}
+
+	var pending []ComparedPR
+	for _, pr := range source {
+		if targetSet[pr.Number] {
+			continue
+		}
+		status := statusPending
+		if pr.RevertedBy != "" {
+			status = statusReverted
+		}
+		pending = append(pending, ComparedPR{PR: pr, Status: status, Direction: direction})
+	}
+
+	return pending
+}
+
+// diffCommits returns the orphan commits of the source branch that are not
+// part of the target branch's history, whether as orphans or as PR commits.
+func diffCommits(source []models.Commit, target models.BranchHistory, direction string) []ComparedCommit {
+	targetSet := make(map[string]bool)
+	for _, commit := range target.Orphans {
+		targetSet[commit.Oid] = true
+	}
+	for _, pr := range target.PRs {
+		targetSet[pr.Oid] = true
+	}
+
+	var pending []ComparedCommit
+	for _, commit := range source {
+		if targetSet[commit.Oid] {
+			continue
+		}
+		status := statusPending
+		if commit.RevertedBy != "" {
+			status = statusReverted
+		}
+		pending = append(pending, ComparedCommit{Commit: commit, Status: status, Direction: direction})
+	}
+
+	return pending
+}
+
+// withoutReverted drops reverted PRs and commits. The reverts themselves go
+// too when the change they undo is also pending, since together they leave
+// nothing to promote.
+func (r comparison) withoutReverted() comparison {
+	reverters := make(map[string]bool)
+	for _, pr := range r.prs {
+		if pr.Status == statusReverted {
+			reverters[pr.RevertedBy] = true
+		}
+	}
+	for _, commit := range r.commits {
+		if commit.Status == statusReverted {
+			reverters[commit.RevertedBy] = true
+		}
+	}
+
+	var kept comparison
+	for _, pr := range r.prs {
+		if pr.Status != statusReverted && !reverters[pr.Oid] {
+			kept.prs = append(kept.prs, pr)
+		}
+	}
+	for _, commit := range r.commits {
+		if commit.Status != statusReverted && !reverters[commit.Oid] {
+			kept.commits = append(kept.commits, commit)
+		}
+	}
+
+	return kept
+}
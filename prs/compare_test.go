@@ -0,0 +1,71 @@
+package prs
+
+import (
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffPRs(t *testing.T) {
+	dev := []models.PR{{Number: 103}, {Number: 101}}
+	main := []models.PR{{Number: 102}, {Number: 101}}
+
+	forward := diffPRs(dev, main, directionForward)
+	assert.Len(t, forward, 1)
+	assert.Equal(t, 103, forward[0].Number)
+	assert.Equal(t, statusPending, forward[0].Status)
+	assert.Equal(t, directionForward, forward[0].Direction)
+
+	reverse := diffPRs(main, dev, directionReverse)
+	assert.Len(t, reverse, 1)
+	assert.Equal(t, 102, reverse[0].Number)
+	assert.Equal(t, directionReverse, reverse[0].Direction)
+
+	assert.Empty(t, diffPRs(dev, dev, directionForward))
+}
+
+func TestDiffPRs_Reverted(t *testing.T) {
+	dev := []models.PR{{Number: 105, Title: `Revert "Feat (#101)"`}, {Number: 101, Title: "Feat", RevertedBy: "abc", RevertedByPR: 105}}
+
+	pending := diffPRs(dev, nil, directionForward)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, statusPending, pending[0].Status)
+	assert.Equal(t, statusReverted, pending[1].Status)
+}
+
+func TestDiffCommits(t *testing.T) {
+	dev := []models.Commit{{Oid: "c3", Subject: "Direct push"}, {Oid: "c2", RevertedBy: "c9"}, {Oid: "c1", Subject: "Shared"}}
+	main := models.BranchHistory{
+		PRs:     []models.PR{{Number: 101, Oid: "c0"}},
+		Orphans: []models.Commit{{Oid: "c1", Subject: "Shared"}},
+	}
+
+	pending := diffCommits(dev, main, directionForward)
+	assert.Len(t, pending, 2)
+	assert.Equal(t, "c3", pending[0].Oid)
+	assert.Equal(t, statusPending, pending[0].Status)
+	assert.Equal(t, directionForward, pending[0].Direction)
+	assert.Equal(t, statusReverted, pending[1].Status)
+
+	assert.Len(t, comparison{commits: pending}.withoutReverted().commits, 1)
+}
+
+func TestComparisonWithoutReverted_DropsPendingRevertPairs(t *testing.T) {
+	result := comparison{
+		prs: []ComparedPR{
+			{PR: models.PR{Number: 105, Oid: "c4"}, Status: statusPending},
+			{PR: models.PR{Number: 101, Oid: "c1", RevertedBy: "c3"}, Status: statusReverted},
+			{PR: models.PR{Number: 102, Oid: "c2", RevertedBy: "c4"}, Status: statusReverted},
+		},
+		commits: []ComparedCommit{
+			{Commit: models.Commit{Oid: "c3"}, Status: statusPending},
+			{Commit: models.Commit{Oid: "c5"}, Status: statusPending},
+		},
+	}
+
+	kept := result.withoutReverted()
+	assert.Empty(t, kept.prs)
+	assert.Len(t, kept.commits, 1)
+	assert.Equal(t, "c5", kept.commits[0].Oid)
+}
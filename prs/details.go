@@ -0,0 +1,131 @@
+package prs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/cli/shurcooL-graphql"
+)
+
+const detailsBatchSize = 50
+
+func (d pullRequestDetails) toPR() models.PR {
+	pr := models.PR{
+		Number:  d.Number,
+		Title:   d.Title,
+		URL:     d.URL,
+		BaseRef: d.BaseRefName,
+	}
+	if d.MergedAt != nil {
+		pr.MergedAt = *d.MergedAt
+	}
+	if d.Author != nil {
+		pr.Author = d.Author.Login
+	}
+	for _, label := range d.Labels.Nodes {
+		pr.Labels = append(pr.Labels, label.Name)
+	}
+	for _, issue := range d.ClosingIssuesReferences.Nodes {
+		pr.ClosingIssues = append(pr.ClosingIssues, issue.Number)
+	}
+
+	return pr
+}
+
+// enrichPRs fills author, labels, merge date, URL, base ref and closing issues
+// for PRs that were recognised from commit messages. The commit's own title
+// is kept so the output still matches the branch history. PRs are looked up
+// in batches, one aliased pullRequest field per PR. Numbers that turn out not
+// to be PRs are left as they are and are not reported as a failure.
+func enrichPRs(ctx context.Context, client models.GQLClient, owner, repo string, prs []models.PR) error {
+	var pending []int
+	for i, pr := range prs {
+		if pr.URL == "" {
+			pending = append(pending, i)
+		}
+	}
+
+	var firstErr error
+	for start := 0; start < len(pending); start += detailsBatchSize {
+		end := min(start+detailsBatchSize, len(pending))
+
+		var numbers []int
+		for _, idx := range pending[start:end] {
+			numbers = append(numbers, prs[idx].Number)
+		}
+
+		details, err := fetchPRDetails(ctx, client, owner, repo, numbers)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil && !onlyNotFound(err) && firstErr == nil {
+			firstErr = err
+		}
+
+		for _, idx := range pending[start:end] {
+			detail, ok := details[prs[idx].Number]
+			if !ok {
+				continue
+			}
+			fetched := detail.toPR()
+			prs[idx].Author = fetched.Author
+			prs[idx].Labels = fetched.Labels
+			prs[idx].MergedAt = fetched.MergedAt
+			prs[idx].URL = fetched.URL
+			prs[idx].BaseRef = fetched.BaseRef
+			prs[idx].ClosingIssues = fetched.ClosingIssues
+		}
+	}
+
+	return firstErr
+}
+
+// onlyNotFound reports whether every error of a details query is about a
+// number GitHub has no PR for, such as an issue or a deleted PR.
+func onlyNotFound(err error) bool {
+	var graphQLErr *api.GraphQLError
+	return errors.As(err, &graphQLErr) && graphQLErr.Match("NOT_FOUND", "repository.")
+}
+
+// fetchPRDetails builds the query struct at runtime because the number of
+// aliased fields depends on the batch. Numbers that are issues or do not
+// exist make GitHub return an error alongside the data for the rest, so the
+// decoded details are returned together with that error.
+func fetchPRDetails(ctx context.Context, client models.GQLClient, owner, repo string, numbers []int) (map[int]pullRequestDetails, error) {
+	fields := make([]reflect.StructField, 0, len(numbers))
+	for _, number := range numbers {
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("PR%d", number),
+			Type: reflect.TypeOf((*pullRequestDetails)(nil)),
+			Tag:  reflect.StructTag(fmt.Sprintf(`graphql:"pr%d: pullRequest(number: %d)"`, number, number)),
+		})
+	}
+
+	queryType := reflect.StructOf([]reflect.StructField{{
+		Name: "Repository",
+		Type: reflect.StructOf(fields),
+		Tag:  `graphql:"repository(owner: $owner, name: $repo)"`,
+	}})
+	query := reflect.New(queryType)
+
+	variables := map[string]any{
+		"owner": graphql.String(owner),
+		"repo":  graphql.String(repo),
+	}
+
+	err := client.Query(ctx, "PullRequestDetails", query.Interface(), variables)
+
+	details := make(map[int]pullRequestDetails)
+	repository := query.Elem().Field(0)
+	for i, number := range numbers {
+		if detail, ok := repository.Field(i).Interface().(*pullRequestDetails); ok && detail != nil {
+			details[number] = *detail
+		}
+	}
+
+	return details, err
+}
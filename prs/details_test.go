@@ -0,0 +1,89 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrichPRs(t *testing.T) {
+	t.Run("Fills details from GitHub", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{`{
+			"repository": {
+				"pr101": {
+					"number": 101,
+					"title": "Feat: New API",
+					"url": "https://github.com/my-org/my-repo/pull/101",
+					"mergedAt": "2024-05-01T10:00:00Z",
+					"baseRefName": "dev",
+					"author": {"login": "jane"},
+					"labels": {"nodes": [{"name": "feature"}]},
+					"closingIssuesReferences": {"nodes": [{"number": 7}]}
+				}
+			}
+		}`}}
+
+		prs := []models.PR{{Number: 101, Title: "Feat: New API (#101)", Author: "Jane Doe"}}
+		err := enrichPRs(context.Background(), client, "my-org", "my-repo", prs)
+		assert.NoError(t, err)
+		assert.Equal(t, "Feat: New API (#101)", prs[0].Title)
+		assert.Equal(t, "jane", prs[0].Author)
+		assert.Equal(t, []string{"feature"}, prs[0].Labels)
+		assert.Equal(t, "2024-05-01T10:00:00Z", prs[0].MergedAt)
+		assert.Equal(t, "https://github.com/my-org/my-repo/pull/101", prs[0].URL)
+		assert.Equal(t, "dev", prs[0].BaseRef)
+		assert.Equal(t, []int{7}, prs[0].ClosingIssues)
+	})
+
+	t.Run("Keeps commit data when the lookup fails", func(t *testing.T) {
+		client := &mockGQLClient{mockErr: fmt.Errorf("API rate limit exceeded")}
+
+		prs := []models.PR{{Number: 101, Title: "Feat: New API (#101)", Author: "Jane Doe"}}
+		err := enrichPRs(context.Background(), client, "my-org", "my-repo", prs)
+		assert.Error(t, err)
+		assert.Equal(t, "Jane Doe", prs[0].Author)
+	})
+
+	t.Run("Does not report numbers that are not PRs", func(t *testing.T) {
+		client := &mockGQLClient{
+			pages: []string{`{"repository": {"pr101": {"number": 101, "url": "https://github.com/my-org/my-repo/pull/101"}, "pr7": null}}`},
+			pageErr: &api.GraphQLError{Errors: []api.GraphQLErrorItem{{
+				Type:    "NOT_FOUND",
+				Message: "Could not resolve to a PullRequest with the number of 7.",
+				Path:    []interface{}{"repository", "pr7"},
+			}}},
+		}
+
+		prs := []models.PR{{Number: 101}, {Number: 7}}
+		assert.NoError(t, enrichPRs(context.Background(), client, "my-org", "my-repo", prs))
+		assert.Equal(t, "https://github.com/my-org/my-repo/pull/101", prs[0].URL)
+		assert.Empty(t, prs[1].URL)
+	})
+
+	t.Run("Reports other errors alongside the details", func(t *testing.T) {
+		client := &mockGQLClient{
+			pages: []string{`{"repository": {"pr101": {"number": 101, "url": "https://github.com/my-org/my-repo/pull/101"}, "pr102": null}}`},
+			pageErr: &api.GraphQLError{Errors: []api.GraphQLErrorItem{{
+				Type:    "FORBIDDEN",
+				Message: "Resource not accessible by integration",
+				Path:    []interface{}{"repository", "pr102"},
+			}}},
+		}
+
+		prs := []models.PR{{Number: 101}, {Number: 102}}
+		assert.Error(t, enrichPRs(context.Background(), client, "my-org", "my-repo", prs))
+		assert.Equal(t, "https://github.com/my-org/my-repo/pull/101", prs[0].URL)
+	})
+
+	t.Run("Skips PRs that already have details", func(t *testing.T) {
+		client := &mockGQLClient{}
+
+		prs := []models.PR{{Number: 101, URL: "https://github.com/my-org/my-repo/pull/101"}}
+		assert.NoError(t, enrichPRs(context.Background(), client, "my-org", "my-repo", prs))
+		assert.Equal(t, 0, client.calls)
+	})
+}
@@ -0,0 +1,190 @@
+package prs
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PRExtractor recognises a pull request reference in a commit message and
+// returns the PR number together with the title to display for it.
+type PRExtractor func(message string) (int, string, bool)
+
+const (
+	extractorsConfigKey = "peddi-tooling.extractors"
+	patternConfigKey    = "peddi-tooling.pattern"
+)
+
+var (
+	mergeCommitRegex    = regexp.MustCompile(`^Merge pull request #(\d+) from \S+`)
+	rebaseTrailerRegex  = regexp.MustCompile(`(?mi)^(?:pull-request|pr):\s*#?(\d+)\s*$`)
+	pullRequestURLRegex = regexp.MustCompile(`https://github\.com/[^/\s]+/[^/\s]+/pull/(\d+)`)
+)
+
+var extractorRegistry = map[string]PRExtractor{
+	"squash": extractSquashReference,
+	"merge":  extractMergeReference,
+	"rebase": extractRebaseReference,
+}
+
+var defaultExtractorNames = []string{"squash", "merge"}
+
+// extractSquashReference matches GitHub's squash-merge convention of
+// appending "(#N)" to the PR title.
+func extractSquashReference(message string) (int, string, bool) {
+	subject := commitSubject(message)
+	if num, ok := extractPRNumber(subject); ok {
+		return num, subject, true
+	}
+
+	return 0, "", false
+}
+
+// extractMergeReference matches "Merge pull request #N from org/branch"
+// commits, whose PR title lives on the first line of the body.
+func extractMergeReference(message string) (int, string, bool) {
+	subject := commitSubject(message)
+	matches := mergeCommitRegex.FindStringSubmatch(subject)
+	if len(matches) != 2 {
+		return 0, "", false
+	}
+
+	num, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, "", false
+	}
+
+	title := subject
+	if _, body, found := strings.Cut(message, "\n"); found {
+		for _, line := range strings.Split(body, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				title = line
+				break
+			}
+		}
+	}
+
+	return num, title, true
+}
+
+// extractRebaseReference matches "PR: #N" / "Pull-Request: #N" trailers and
+// pull request URLs. GitHub's own rebase merges add neither, so it only finds
+// rebased commits whose workflow writes them into the message; the others
+// need --associated.
+func extractRebaseReference(message string) (int, string, bool) {
+	for _, re := range []*regexp.Regexp{rebaseTrailerRegex, pullRequestURLRegex} {
+		matches := re.FindStringSubmatch(message)
+		if len(matches) != 2 {
+			continue
+		}
+		if num, err := strconv.Atoi(matches[1]); err == nil {
+			return num, commitSubject(message), true
+		}
+	}
+
+	return 0, "", false
+}
+
+func newPatternExtractor(pattern string) (PRExtractor, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PR pattern '%s': %w", pattern, err)
+	}
+	if re.NumSubexp() < 1 {
+		return nil, fmt.Errorf("PR pattern '%s' must contain a capture group for the PR number", pattern)
+	}
+
+	return func(message string) (int, string, bool) {
+		matches := re.FindStringSubmatch(message)
+		if len(matches) < 2 {
+			return 0, "", false
+		}
+		num, err := strconv.Atoi(matches[1])
+		if err != nil {
+			return 0, "", false
+		}
+
+		return num, commitSubject(message), true
+	}, nil
+}
+
+// resolveExtractors builds the extractor chain for the current repository.
+// Flags take precedence over the repo's git config, which takes precedence
+// over the defaults. Custom patterns from both sources run after the named
+// extractors.
+func resolveExtractors(names []string, patterns []string) ([]PRExtractor, string, error) {
+	if len(names) == 0 {
+		for _, value := range readGitConfigValues(extractorsConfigKey) {
+			names = append(names, splitList(value)...)
+		}
+	}
+	if len(names) == 0 {
+		names = defaultExtractorNames
+	}
+	patterns = append(readGitConfigValues(patternConfigKey), patterns...)
+
+	var extractors []PRExtractor
+	for _, name := range names {
+		extractor, ok := extractorRegistry[name]
+		if !ok {
+			return nil, "", fmt.Errorf("unknown PR extractor '%s' (available: %s)", name, strings.Join(availableExtractorNames(), ", "))
+		}
+		extractors = append(extractors, extractor)
+	}
+
+	for _, pattern := range patterns {
+		extractor, err := newPatternExtractor(pattern)
+		if err != nil {
+			return nil, "", err
+		}
+		extractors = append(extractors, extractor)
+	}
+
+	signature := strings.Join(append(append([]string{}, names...), patterns...), ",")
+
+	return extractors, signature, nil
+}
+
+func availableExtractorNames() []string {
+	var names []string
+	for name := range extractorRegistry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func readGitConfigValues(key string) []string {
+	out, err := exec.Command("git", "config", "--get-all", key).Output()
+	if err != nil {
+		return nil
+	}
+
+	var values []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+
+	return values
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+func commitSubject(message string) string {
+	return strings.SplitN(message, "\n", 2)[0]
+}
@@ -0,0 +1,110 @@
+package prs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		extractor     PRExtractor
+		message       string
+		expectedNum   int
+		expectedTitle string
+		expectedOk    bool
+	}{
+		{
+			name:          "Squash matches title suffix",
+			extractor:     extractSquashReference,
+			message:       "Fix: Bug (#102)\n\n* commit one\n* commit two (#7)",
+			expectedNum:   102,
+			expectedTitle: "Fix: Bug (#102)",
+			expectedOk:    true,
+		},
+		{
+			name:       "Squash ignores references in the body",
+			extractor:  extractSquashReference,
+			message:    "Fix: Bug\n\nFollow-up to (#7)",
+			expectedOk: false,
+		},
+		{
+			name:          "Merge commit uses body as title",
+			extractor:     extractMergeReference,
+			message:       "Merge pull request #55 from my-org/feature/login\n\nAdd login page",
+			expectedNum:   55,
+			expectedTitle: "Add login page",
+			expectedOk:    true,
+		},
+		{
+			name:          "Merge commit without body falls back to subject",
+			extractor:     extractMergeReference,
+			message:       "Merge pull request #55 from my-org/feature/login",
+			expectedNum:   55,
+			expectedTitle: "Merge pull request #55 from my-org/feature/login",
+			expectedOk:    true,
+		},
+		{
+			name:       "Merge ignores branch merges",
+			extractor:  extractMergeReference,
+			message:    "Merge branch 'dev' into main",
+			expectedOk: false,
+		},
+		{
+			name:          "Rebase trailer",
+			extractor:     extractRebaseReference,
+			message:       "Add login page\n\nPull-Request: #61",
+			expectedNum:   61,
+			expectedTitle: "Add login page",
+			expectedOk:    true,
+		},
+		{
+			name:          "Rebase pull request URL",
+			extractor:     extractRebaseReference,
+			message:       "Add login page\n\nSee https://github.com/my-org/my-repo/pull/62",
+			expectedNum:   62,
+			expectedTitle: "Add login page",
+			expectedOk:    true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			num, title, ok := tc.extractor(tc.message)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedNum, num)
+			assert.Equal(t, tc.expectedTitle, title)
+		})
+	}
+}
+
+func TestResolveExtractors(t *testing.T) {
+	t.Run("Custom pattern", func(t *testing.T) {
+		extractors, signature, err := resolveExtractors([]string{"squash"}, []string{`\[PR-(\d+)\]`})
+		assert.NoError(t, err)
+		assert.Len(t, extractors, 2)
+		assert.Contains(t, signature, `\[PR-(\d+)\]`)
+
+		commits := []Commit{
+			{Oid: "c2", Message: "[PR-12] Custom convention"},
+			{Oid: "c1", Message: "Squashed (#11)"},
+		}
+		prs, _ := extractPRsFromCommits(commits, extractors)
+		assert.Len(t, prs, 2)
+		assert.Equal(t, 12, prs[0].Number)
+		assert.Equal(t, 11, prs[1].Number)
+	})
+
+	t.Run("Unknown extractor", func(t *testing.T) {
+		_, _, err := resolveExtractors([]string{"octopus"}, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown PR extractor")
+	})
+
+	t.Run("Pattern without capture group", func(t *testing.T) {
+		_, _, err := resolveExtractors([]string{"squash"}, []string{`PR-\d+`})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "capture group")
+	})
+}
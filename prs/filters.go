@@ -0,0 +1,177 @@
+package prs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/astein-peddi/git-tooling/models"
+)
+
+const filterDateLayout = "2006-01-02"
+
+// historyFilter narrows the results down to one team's slice of a release.
+// Paths are applied while scanning, so only commits touching them are read.
+// The other filters run on the comparison, never on the branch histories: a
+// PR left out of the target branch's history would show up as missing from
+// it.
+type historyFilter struct {
+	authors []string
+	labels  []string
+	since   time.Time
+	until   time.Time
+	paths   []string
+}
+
+func newHistoryFilter(authors, labels []string, since, until string, paths []string) (historyFilter, error) {
+	filter := historyFilter{authors: authors, labels: labels, paths: paths}
+
+	var err error
+	if filter.since, err = parseFilterDate("since", since); err != nil {
+		return historyFilter{}, err
+	}
+	if filter.until, err = parseFilterDate("until", until); err != nil {
+		return historyFilter{}, err
+	}
+	// A plain date includes the whole day.
+	if until != "" && len(until) == len(filterDateLayout) {
+		filter.until = filter.until.Add(24*time.Hour - time.Nanosecond)
+	}
+	if !filter.since.IsZero() && !filter.until.IsZero() && filter.until.Before(filter.since) {
+		return historyFilter{}, fmt.Errorf("--until %s is before --since %s", until, since)
+	}
+
+	return filter, nil
+}
+
+func parseFilterDate(flag, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range []string{filterDateLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --%s '%s': use YYYY-MM-DD or an RFC 3339 timestamp", flag, value)
+}
+
+func (f historyFilter) isEmpty() bool {
+	return len(f.authors) == 0 && len(f.labels) == 0 && f.since.IsZero() && f.until.IsZero() && len(f.paths) == 0
+}
+
+// scope is the part of the cache key that keeps histories scanned with
+// different paths apart. The other filters do not change what is scanned.
+func (f historyFilter) scope() string {
+	if len(f.paths) == 0 {
+		return ""
+	}
+
+	sorted := append([]string{}, f.paths...)
+	sort.Strings(sorted)
+
+	return "filter:path=" + strings.Join(sorted, ",")
+}
+
+// apply keeps the PRs and orphan commits of a comparison that match every
+// filter. Orphan commits have no labels, so a label filter drops all of them.
+func (f historyFilter) apply(result comparison) comparison {
+	if f.isEmpty() {
+		return result
+	}
+
+	filtered := comparison{snapshots: result.snapshots}
+	for _, pr := range result.prs {
+		if f.matchesPR(pr.PR) {
+			filtered.prs = append(filtered.prs, pr)
+		}
+	}
+	for _, commit := range result.commits {
+		if len(f.labels) == 0 && f.matchesAuthor(commit.Author) && f.matchesDate(commit.Date) {
+			filtered.commits = append(filtered.commits, commit)
+		}
+	}
+
+	return filtered
+}
+
+// applyMatrix keeps the matrix rows whose PR matches every filter.
+func (f historyFilter) applyMatrix(rows []MatrixRow) []MatrixRow {
+	if f.isEmpty() {
+		return rows
+	}
+
+	var filtered []MatrixRow
+	for _, row := range rows {
+		if f.matchesPR(row.PR) {
+			filtered = append(filtered, row)
+		}
+	}
+
+	return filtered
+}
+
+// withoutDetails drops the filters a preview cannot apply before PR details
+// are loaded: labels, and authors unless they are git authors in local mode.
+// It also reports whether any filter was dropped.
+func (f historyFilter) withoutDetails(isLocal bool) (historyFilter, bool) {
+	dropped := len(f.labels) > 0 || (!isLocal && len(f.authors) > 0)
+	f.labels = nil
+	if !isLocal {
+		f.authors = nil
+	}
+
+	return f, dropped
+}
+
+func (f historyFilter) matchesPR(pr models.PR) bool {
+	return f.matchesAuthor(pr.Author) && f.matchesLabels(pr.Labels) && f.matchesDate(pr.MergedAt)
+}
+
+func (f historyFilter) matchesAuthor(author string) bool {
+	if len(f.authors) == 0 {
+		return true
+	}
+
+	for _, candidate := range f.authors {
+		if strings.EqualFold(candidate, author) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f historyFilter) matchesLabels(labels []string) bool {
+	if len(f.labels) == 0 {
+		return true
+	}
+
+	for _, wanted := range f.labels {
+		for _, label := range labels {
+			if strings.EqualFold(wanted, label) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchesDate reports whether a timestamp falls in the --since/--until range.
+// Entries without a readable date are kept out of a bounded range.
+func (f historyFilter) matchesDate(timestamp string) bool {
+	if f.since.IsZero() && f.until.IsZero() {
+		return true
+	}
+
+	t, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return false
+	}
+
+	return !t.Before(f.since) && (f.until.IsZero() || !t.After(f.until))
+}
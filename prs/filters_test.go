@@ -0,0 +1,105 @@
+package prs
+
+import (
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHistoryFilter(t *testing.T) {
+	t.Run("Plain until date includes the whole day", func(t *testing.T) {
+		filter, err := newHistoryFilter(nil, nil, "2024-03-01", "2024-03-31", nil)
+		assert.NoError(t, err)
+		assert.True(t, filter.matchesDate("2024-03-31T23:59:59Z"))
+		assert.False(t, filter.matchesDate("2024-04-01T00:00:00Z"))
+		assert.False(t, filter.matchesDate("2024-02-29T23:59:59Z"))
+	})
+
+	t.Run("Rejects invalid dates", func(t *testing.T) {
+		_, err := newHistoryFilter(nil, nil, "last week", "", nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid --since 'last week'")
+	})
+
+	t.Run("Rejects an inverted range", func(t *testing.T) {
+		_, err := newHistoryFilter(nil, nil, "2024-03-31", "2024-03-01", nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "is before --since")
+	})
+}
+
+func TestHistoryFilterScope(t *testing.T) {
+	empty, _ := newHistoryFilter(nil, nil, "", "", nil)
+	assert.Equal(t, "", empty.scope())
+
+	a, _ := newHistoryFilter([]string{"Bob", "alice"}, []string{"hotfix"}, "2024-03-01", "", []string{"svc/b", "svc/a"})
+	b, _ := newHistoryFilter(nil, nil, "", "", []string{"svc/a", "svc/b"})
+	assert.Equal(t, a.scope(), b.scope())
+	assert.Equal(t, "filter:path=svc/a,svc/b", a.scope())
+
+	unscoped, _ := newHistoryFilter([]string{"alice"}, []string{"hotfix"}, "2024-03-01", "", nil)
+	assert.Equal(t, "", unscoped.scope(), "filters other than --path do not change what is scanned")
+}
+
+func TestHistoryFilterApply(t *testing.T) {
+	result := comparison{
+		prs: []ComparedPR{
+			{PR: models.PR{Number: 3, Author: "alice", Labels: []string{"Team-A"}, MergedAt: "2024-03-15T10:00:00Z"}},
+			{PR: models.PR{Number: 2, Author: "bob", Labels: []string{"team-a"}, MergedAt: "2024-03-10T10:00:00Z"}},
+			{PR: models.PR{Number: 1, Author: "Alice", MergedAt: "2024-02-01T10:00:00Z"}},
+		},
+		commits: []ComparedCommit{
+			{Commit: models.Commit{Oid: "c2", Author: "alice", Date: "2024-03-12T10:00:00Z"}},
+			{Commit: models.Commit{Oid: "c1", Author: "bob", Date: "2024-03-12T10:00:00Z"}},
+		},
+	}
+
+	t.Run("Author and date", func(t *testing.T) {
+		filter, _ := newHistoryFilter([]string{"alice"}, nil, "2024-03-01", "", nil)
+		filtered := filter.apply(result)
+		assert.Len(t, filtered.prs, 1)
+		assert.Equal(t, 3, filtered.prs[0].Number)
+		assert.Len(t, filtered.commits, 1)
+		assert.Equal(t, "c2", filtered.commits[0].Oid)
+	})
+
+	t.Run("Label drops orphan commits", func(t *testing.T) {
+		filter, _ := newHistoryFilter(nil, []string{"TEAM-A"}, "", "", nil)
+		filtered := filter.apply(result)
+		assert.Len(t, filtered.prs, 2)
+		assert.Empty(t, filtered.commits)
+	})
+
+	t.Run("No filter keeps everything", func(t *testing.T) {
+		filter, _ := newHistoryFilter(nil, nil, "", "", nil)
+		assert.Equal(t, result, filter.apply(result))
+	})
+
+	t.Run("Previews skip the filters that need PR details", func(t *testing.T) {
+		filter, _ := newHistoryFilter([]string{"alice"}, []string{"team-a"}, "2024-03-01", "", nil)
+
+		preview, dropped := filter.withoutDetails(false)
+		assert.True(t, dropped)
+		assert.Len(t, preview.apply(result).prs, 2, "only the date filter applies")
+
+		local, dropped := filter.withoutDetails(true)
+		assert.True(t, dropped)
+		assert.Equal(t, []string{"alice"}, local.authors)
+
+		dateOnly, _ := newHistoryFilter(nil, nil, "2024-03-01", "", nil)
+		_, dropped = dateOnly.withoutDetails(false)
+		assert.False(t, dropped)
+	})
+
+	t.Run("The target branch is never filtered", func(t *testing.T) {
+		// #5 was merged into dev before --since and cherry-picked to main
+		// after it, so main's copy matches the range and dev's does not.
+		filter, _ := newHistoryFilter(nil, nil, "2024-03-01", "", nil)
+		config := scanConfig{filter: filter}
+		main := models.BranchHistory{PRs: []models.PR{{Number: 5, Oid: "b5", MergedAt: "2024-03-02T10:00:00Z"}}}
+		dev := models.BranchHistory{PRs: []models.PR{{Number: 5, Oid: "a5", MergedAt: "2024-02-28T10:00:00Z"}}}
+
+		assert.Empty(t, config.compare(main, dev, false).prs)
+	})
+}
@@ -0,0 +1,116 @@
+package prs
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/astein-peddi/git-tooling/output"
+)
+
+// prJSONFields is the documented --json schema of prs results. PRs and orphan
+// commits share it; fields a record does not have are output as null.
+var prJSONFields = []string{
+	"number", "title", "author", "labels", "mergedAt", "url", "baseRef", "closingIssues",
+	"oid", "subject", "date", "status", "direction", "backportedAs", "revertedBy", "revertedByPR",
+}
+
+// resultData is what --template receives: the PR list, or an object with the
+// PRs and the orphan commits when --commits is set.
+func resultData(prs []ComparedPR, commits []ComparedCommit, showCommits bool) any {
+	if !showCommits {
+		return prs
+	}
+
+	return struct {
+		PRs     []ComparedPR     `json:"prs"`
+		Commits []ComparedCommit `json:"commits"`
+	}{prs, commits}
+}
+
+// writeJSON prints the selected fields of each PR, wrapped together with the
+// orphan commits in the same shape as resultData when --commits is set.
+func writeJSON(exporter *output.JSONExporter, prs []ComparedPR, commits []ComparedCommit, showCommits bool) error {
+	selectedPRs, err := exporter.Select(prs)
+	if err != nil {
+		return err
+	}
+	if !showCommits {
+		return exporter.Write(os.Stdout, selectedPRs)
+	}
+
+	selectedCommits, err := exporter.Select(commits)
+	if err != nil {
+		return err
+	}
+
+	return exporter.Write(os.Stdout, map[string]any{"prs": selectedPRs, "commits": selectedCommits})
+}
+
+// resultTable flattens PRs and orphan commits into the rows rendered by
+// --format. Commits follow the PRs and are identified by their short SHA.
+func resultTable(prs []ComparedPR, commits []ComparedCommit, symmetric bool) output.Table {
+	table := output.Table{Headers: []string{"Number", "Title", "Author", "Merged", "Labels", "Base", "Closes", "Status"}}
+	if symmetric {
+		table.Headers = append(table.Headers, "Direction")
+	}
+	table.Headers = append(table.Headers, "URL")
+
+	addRow := func(row []string, direction, url string) {
+		if symmetric {
+			row = append(row, direction)
+		}
+		table.Rows = append(table.Rows, append(row, url))
+	}
+
+	for _, pr := range prs {
+		addRow([]string{
+			fmt.Sprintf("#%d", pr.Number),
+			pr.Title,
+			pr.Author,
+			shortDate(pr.MergedAt),
+			strings.Join(pr.Labels, ", "),
+			pr.BaseRef,
+			issueList(pr.ClosingIssues),
+			statusText(pr),
+		}, pr.Direction, pr.URL)
+		table.Records = append(table.Records, pr)
+	}
+
+	for _, commit := range commits {
+		addRow([]string{
+			shortOid(commit.Oid),
+			commit.Subject,
+			commit.Author,
+			shortDate(commit.Date),
+			"",
+			"",
+			"",
+			commitStatusText(commit),
+		}, commit.Direction, commit.URL)
+		table.Records = append(table.Records, commit)
+	}
+
+	return table
+}
+
+// matrixTable is the plain version of the matrix view, one ✓ or ✗ column per
+// branch, for output that does not go to a terminal.
+func matrixTable(branches []string, rows []MatrixRow) output.Table {
+	table := output.Table{Headers: append(append([]string{"Number", "Title"}, branches...), "Skipped")}
+
+	for _, row := range rows {
+		cells := []string{fmt.Sprintf("#%d", row.Number), row.Title}
+		for _, branch := range branches {
+			presence := "✗"
+			if row.Branches[branch] {
+				presence = "✓"
+			}
+			cells = append(cells, presence)
+		}
+		table.Rows = append(table.Rows, append(cells, strings.Join(row.Skipped, ", ")))
+		table.Records = append(table.Records, row)
+	}
+
+	return table
+}
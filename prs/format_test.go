@@ -0,0 +1,38 @@
+package prs
+
+import (
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultTable(t *testing.T) {
+	prs := []ComparedPR{
+		{PR: models.PR{Number: 101, Title: "Feat", Author: "jane", MergedAt: "2024-05-01T10:00:00Z", Labels: []string{"feature"}, URL: "https://example.com/101", BaseRef: "dev", ClosingIssues: []int{7, 9}}, Status: statusPending, Direction: directionForward},
+	}
+	commits := []ComparedCommit{
+		{Commit: models.Commit{Oid: "0123456789abcdef", Subject: "Direct push", Author: "john"}, Status: statusPending, Direction: directionReverse},
+	}
+
+	table := resultTable(prs, commits, true)
+	assert.Equal(t, []string{"Number", "Title", "Author", "Merged", "Labels", "Base", "Closes", "Status", "Direction", "URL"}, table.Headers)
+	assert.Equal(t, [][]string{
+		{"#101", "Feat", "jane", "2024-05-01", "feature", "dev", "#7 #9", "pending", "forward", "https://example.com/101"},
+		{"0123456", "Direct push", "john", "", "", "", "", "pending", "reverse", ""},
+	}, table.Rows)
+	assert.Equal(t, []any{prs[0], commits[0]}, table.Records)
+
+	assert.Len(t, resultTable(prs, nil, false).Headers, 9)
+}
+
+func TestMatrixTable(t *testing.T) {
+	rows := []MatrixRow{
+		{PR: models.PR{Number: 7, Title: "Hotfix"}, Branches: map[string]bool{"dev": false, "main": true}, Skipped: []string{"dev"}},
+	}
+
+	table := matrixTable([]string{"dev", "main"}, rows)
+	assert.Equal(t, []string{"Number", "Title", "dev", "main", "Skipped"}, table.Headers)
+	assert.Equal(t, [][]string{{"#7", "Hotfix", "✗", "✓", "dev"}}, table.Rows)
+	assert.Equal(t, []any{rows[0]}, table.Records)
+}
@@ -0,0 +1,167 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/astein-peddi/git-tooling/models"
+)
+
+const (
+	logFieldSeparator  = "\x00"
+	logRecordSeparator = "\x1e"
+)
+
+func FetchCommitsForLocalBranch(ctx context.Context, branch string, limit int, opts ScanOptions) ([]models.CommitRecord, error) {
+	revision := branch
+	if opts.Revision != "" {
+		revision = opts.Revision
+	}
+
+	commits, err := fetchLocalCommitsInBranch(ctx, revision, limit, opts.StopAt, opts.Paths)
+	if err != nil {
+		return nil, err
+	}
+	if len(opts.Paths) > 0 {
+		if err := attributeMergedCommits(ctx, revision, opts.StopAt, commits, opts.Extractors); err != nil {
+			return nil, err
+		}
+	}
+
+	return commitRecords(commits, opts.Extractors), nil
+}
+
+// fetchLocalCommitsInBranch reads the history with git log. With paths,
+// --show-pulls keeps the merge commits that bring changes to them in, which
+// git's history simplification would otherwise drop.
+func fetchLocalCommitsInBranch(ctx context.Context, branch string, limit int, stopAt []string, paths []string) ([]Commit, error) {
+	args := []string{"log", "--format=%H%x00%P%x00%an%x00%cI%x00%B%x1e"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("--max-count=%d", limit))
+	}
+	if len(paths) > 0 {
+		args = append(args, "--show-pulls")
+	}
+	args = append(args, branch)
+	for _, oid := range stopAt {
+		args = append(args, "^"+oid)
+	}
+	args = append(args, "--")
+	args = append(args, paths...)
+
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local history for branch '%s': %w", branch, err)
+	}
+
+	return parseGitLog(string(out)), nil
+}
+
+// attributeMergedCommits credits the commits a path-filtered history lists
+// from a merged PR branch to that PR, as the merge commit does in a full
+// scan. The filtered history skips the branch commits that leave the paths
+// alone, and git log shows simplified parents there, so the merges are walked
+// on the full commit graph of the range, whose parents also replace the
+// simplified ones.
+func attributeMergedCommits(ctx context.Context, revision string, stopAt []string, commits []Commit, extractors []PRExtractor) error {
+	graph, err := fetchLocalCommitGraph(ctx, revision, stopAt)
+	if err != nil {
+		return err
+	}
+
+	parents := make(map[string][]string, len(graph))
+	for _, commit := range graph {
+		parents[commit.Oid] = commit.ParentOids
+	}
+	for i := range commits {
+		if oids, ok := parents[commits[i].Oid]; ok {
+			commits[i].Parents = len(oids)
+			commits[i].ParentOids = oids
+		}
+	}
+
+	resolved := resolveCommits(commits, extractors)
+	merges := make(map[string]models.PR)
+	for i, r := range resolved {
+		if r.ok && commits[i].Parents > 1 {
+			merges[commits[i].Oid] = r.pr
+		}
+	}
+	if len(merges) == 0 {
+		return nil
+	}
+
+	graphResolved := make([]resolvedCommit, len(graph))
+	for i, commit := range graph {
+		pr, ok := merges[commit.Oid]
+		graphResolved[i] = resolvedCommit{pr: pr, ok: ok}
+	}
+	merged := mergedPRCommits(graph, graphResolved)
+
+	for i := range commits {
+		if resolved[i].ok {
+			continue
+		}
+		if merge, ok := merged[commits[i].Oid]; ok {
+			pr := graphResolved[merge].pr
+			commits[i].AssociatedPR = &pr
+		}
+	}
+
+	return nil
+}
+
+// fetchLocalCommitGraph lists every commit of the range with its parents,
+// each commit before its parents.
+func fetchLocalCommitGraph(ctx context.Context, revision string, stopAt []string) ([]Commit, error) {
+	args := []string{"rev-list", "--topo-order", "--parents", revision}
+	for _, oid := range stopAt {
+		args = append(args, "^"+oid)
+	}
+	args = append(args, "--")
+
+	out, err := exec.CommandContext(ctx, "git", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the commit graph of '%s': %w", revision, err)
+	}
+
+	var graph []Commit
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		graph = append(graph, Commit{Oid: fields[0], Parents: len(fields) - 1, ParentOids: fields[1:]})
+	}
+
+	return graph, nil
+}
+
+func parseGitLog(output string) []Commit {
+	var commits []Commit
+	for _, record := range strings.Split(output, logRecordSeparator) {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+
+		fields := strings.SplitN(record, logFieldSeparator, 5)
+		if len(fields) != 5 {
+			continue
+		}
+
+		parents := strings.Fields(fields[1])
+		commits = append(commits, Commit{
+			Oid:        fields[0],
+			Parents:    len(parents),
+			ParentOids: parents,
+			Author:     fields[2],
+			Date:       fields[3],
+			Message:    strings.TrimRight(fields[4], "\n"),
+		})
+	}
+
+	return commits
+}
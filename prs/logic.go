@@ -1,41 +1,341 @@
 package prs
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/astein-peddi/git-tooling/cache"
+	"github.com/astein-peddi/git-tooling/loader"
 	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/utils"
 	"github.com/cli/shurcooL-graphql"
 )
 
 var pullRequestRegex = regexp.MustCompile(`\(#(\d+)\)`)
 
-func FetchPRsForBranch(client models.GQLClient, owner, repo, branch string, limit int) ([]models.PR, error) {
-	commits, err := fetchCommitsInBranch(client, owner, repo, branch, limit)
+// FetchCommitsForBranch scans a branch through the API and resolves each
+// commit to its PR, with the PR details filled in. When the details cannot be
+// loaded, the records are still returned, with cache.ErrIncomplete, so they
+// are used without being cached.
+func FetchCommitsForBranch(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, opts ScanOptions) ([]models.CommitRecord, error) {
+	commits, err := fetchBranchCommits(ctx, client, owner, repo, branch, limit, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	seen := make(map[int]bool)
+	records := commitRecords(commits, opts.Extractors)
+	if err := enrichRecords(ctx, client, owner, repo, records); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		fmt.Fprintf(os.Stderr, "Warning: could not load PR details for branch '%s': %v\n", branch, err)
+		return records, fmt.Errorf("%w: %v", cache.ErrIncomplete, err)
+	}
+
+	return records, nil
+}
+
+// historyFromRecords rebuilds a branch history from its commit records. The
+// PR each record resolved to is reused as is, so only revert detection and
+// the orphan commits are worked out again.
+func historyFromRecords(records []models.CommitRecord, extractors []PRExtractor) models.BranchHistory {
+	commits := make([]Commit, len(records))
+	for i, record := range records {
+		commits[i] = Commit{
+			Oid:          record.Oid,
+			Message:      record.Message,
+			Author:       record.Author,
+			Date:         record.Date,
+			URL:          record.URL,
+			Parents:      record.Parents,
+			ParentOids:   record.ParentOids,
+			AssociatedPR: record.PR,
+		}
+	}
+
+	prs, orphans := extractPRsFromCommits(commits, extractors)
+
+	return models.BranchHistory{PRs: prs, Orphans: orphans}
+}
+
+func commitRecords(commits []Commit, extractors []PRExtractor) []models.CommitRecord {
+	resolved := resolveCommits(commits, extractors)
+
+	records := make([]models.CommitRecord, len(commits))
+	for i, commit := range commits {
+		records[i] = models.CommitRecord{
+			Oid:        commit.Oid,
+			Message:    commit.Message,
+			Author:     commit.Author,
+			Date:       commit.Date,
+			URL:        commit.URL,
+			Parents:    commit.Parents,
+			ParentOids: commit.ParentOids,
+		}
+		if resolved[i].ok {
+			pr := resolved[i].pr
+			records[i].PR = &pr
+		}
+	}
+
+	return records
+}
+
+// enrichRecords loads the details of every PR the records resolved to, once
+// per PR number.
+func enrichRecords(ctx context.Context, client models.GQLClient, owner, repo string, records []models.CommitRecord) error {
+	index := make(map[int]int)
 	var prs []models.PR
+	for _, record := range records {
+		if record.PR == nil {
+			continue
+		}
+		if _, seen := index[record.PR.Number]; !seen {
+			index[record.PR.Number] = len(prs)
+			prs = append(prs, *record.PR)
+		}
+	}
+
+	err := enrichPRs(ctx, client, owner, repo, prs)
 
-	for _, commit := range commits {
-		if prNum, ok := extractPRNumber(commit.Message); ok {
-			if !seen[prNum] {
-				title := strings.SplitN(commit.Message, "\n", 2)[0]
-				prs = append(prs, models.PR{Number: prNum, Title: title})
-				seen[prNum] = true
+	for i, record := range records {
+		if record.PR == nil {
+			continue
+		}
+		enriched := prs[index[record.PR.Number]]
+		enriched.Oid = record.Oid
+		records[i].PR = &enriched
+	}
+
+	return err
+}
+
+type resolvedCommit struct {
+	pr models.PR
+	ok bool
+}
+
+// extractPRsFromCommits returns the PRs found in a branch history, newest
+// first, together with the orphan commits that match no PR. Merge commits
+// without a PR reference are not orphans: the commits they bring in are
+// reported on their own. The commits a PR merge commit brings in belong to
+// that PR, so they are not orphans either.
+func extractPRsFromCommits(commits []Commit, extractors []PRExtractor) ([]models.PR, []models.Commit) {
+	resolved := resolveCommits(commits, extractors)
+	revertedBy := detectReverts(commits, resolved)
+	merged := mergedPRCommits(commits, resolved)
+
+	index := make(map[int]int)
+	var prs []models.PR
+	var orphans []models.Commit
+
+	for i, r := range resolved {
+		if !r.ok {
+			if _, fromMerge := merged[commits[i].Oid]; commits[i].Parents <= 1 && !fromMerge {
+				orphans = append(orphans, newOrphanCommit(commits, resolved, revertedBy, i))
+			}
+			continue
+		}
+		pos, seen := index[r.pr.Number]
+		if !seen {
+			prs = append(prs, r.pr)
+			pos = len(prs) - 1
+			index[r.pr.Number] = pos
+		}
+
+		if reverter, reverted := revertedBy[i]; reverted && prs[pos].RevertedBy == "" {
+			prs[pos].RevertedBy = commits[reverter].Oid
+			if resolved[reverter].ok {
+				prs[pos].RevertedByPR = resolved[reverter].pr.Number
+			}
+		}
+	}
+
+	return prs, orphans
+}
+
+// mergedPRCommits returns the commits that PR merge commits brought in: those
+// reachable from a merge's second or later parents but not from its first,
+// as far as the history lists them. Each one maps to the index of the newest
+// merge that brought it in.
+func mergedPRCommits(commits []Commit, resolved []resolvedCommit) map[string]int {
+	merged := make(map[string]int)
+	for i, r := range resolved {
+		if !r.ok || len(commits[i].ParentOids) < 2 {
+			continue
+		}
+
+		mainline := map[string]bool{commits[i].ParentOids[0]: true}
+		pending := make(map[string]bool)
+		for _, parent := range commits[i].ParentOids[1:] {
+			pending[parent] = true
+		}
+
+		for _, commit := range commits[i+1:] {
+			if len(pending) == 0 {
+				break
+			}
+			if mainline[commit.Oid] {
+				for _, parent := range commit.ParentOids {
+					mainline[parent] = true
+					delete(pending, parent)
+				}
+				continue
+			}
+			if !pending[commit.Oid] {
+				continue
+			}
+
+			delete(pending, commit.Oid)
+			if _, seen := merged[commit.Oid]; !seen {
+				merged[commit.Oid] = i
+			}
+			for _, parent := range commit.ParentOids {
+				if !mainline[parent] {
+					pending[parent] = true
+				}
 			}
 		}
 	}
 
-	return prs, nil
+	return merged
 }
 
-func fetchCommitsInBranch(client models.GQLClient, owner, repo, branch string, limit int) ([]Commit, error) {
+func resolveCommits(commits []Commit, extractors []PRExtractor) []resolvedCommit {
+	resolved := make([]resolvedCommit, len(commits))
+	for i, commit := range commits {
+		pr, ok := resolveCommitPR(commit, extractors)
+		// A plain `git revert` of a squash merge quotes the "(#N)" subject of
+		// the PR it undoes, which must not be mistaken for that PR itself.
+		if target, isRevert := parseRevert(commit.Message); ok && isRevert && commit.AssociatedPR == nil && target.title != "" {
+			unquoted := commit
+			unquoted.Message = strings.Replace(commit.Message, `"`+target.title+`"`, "", 1)
+			own, hasOwn := resolveCommitPR(unquoted, extractors)
+			ok = hasOwn && own.Number == pr.Number
+		}
+		resolved[i] = resolvedCommit{pr: pr, ok: ok}
+	}
+
+	return resolved
+}
+
+func newOrphanCommit(commits []Commit, resolved []resolvedCommit, revertedBy map[int]int, i int) models.Commit {
+	orphan := models.Commit{
+		Oid:     commits[i].Oid,
+		Subject: commitSubject(commits[i].Message),
+		Author:  commits[i].Author,
+		Date:    commits[i].Date,
+		URL:     commits[i].URL,
+	}
+	if reverter, reverted := revertedBy[i]; reverted {
+		orphan.RevertedBy = commits[reverter].Oid
+		if resolved[reverter].ok {
+			orphan.RevertedByPR = resolved[reverter].pr.Number
+		}
+	}
+
+	return orphan
+}
+
+func resolveCommitPR(commit Commit, extractors []PRExtractor) (models.PR, bool) {
+	if commit.AssociatedPR != nil {
+		pr := *commit.AssociatedPR
+		pr.Oid = commit.Oid
+		return pr, true
+	}
+
+	for _, extract := range extractors {
+		if prNum, title, ok := extract(commit.Message); ok {
+			return models.PR{Number: prNum, Title: title, Author: commit.Author, MergedAt: commit.Date, Oid: commit.Oid}, true
+		}
+	}
+
+	return models.PR{}, false
+}
+
+// fetchBranchCommits reads the branch history from opts.Revision when it is
+// set, or from the branch head otherwise, restricted to the commits that
+// touch opts.Paths when any are given. The history connection takes a single
+// path, so each one is queried on its own and the results are merged. A
+// path-filtered history leaves out the merge commits of merged PRs, so the
+// commits are resolved through their associated PRs there.
+func fetchBranchCommits(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, opts ScanOptions) ([]Commit, error) {
+	revision := branch
+	if opts.Revision != "" {
+		revision = opts.Revision
+	}
+
+	if len(opts.Paths) == 0 {
+		return fetchCommitsInBranch(ctx, client, owner, repo, revision, limit, opts.StopAt, "", opts.Associated, opts.Progress, opts.OnPage)
+	}
+
+	var histories [][]Commit
+	for _, path := range opts.Paths {
+		onPage := opts.OnPage
+		if onPage != nil {
+			previous := histories
+			onPage = func(commits []Commit) {
+				opts.OnPage(mergeHistories(append(previous[:len(previous):len(previous)], commits), limit))
+			}
+		}
+
+		commits, err := fetchCommitsInBranch(ctx, client, owner, repo, revision, limit, opts.StopAt, path, true, opts.Progress, onPage)
+		if err != nil {
+			return nil, err
+		}
+		histories = append(histories, commits)
+	}
+
+	return mergeHistories(histories, limit), nil
+}
+
+// mergeHistories combines newest-first histories into one, dropping commits
+// that appear in more than one of them.
+func mergeHistories(histories [][]Commit, limit int) []Commit {
+	seen := make(map[string]bool)
+	var merged []Commit
+	for _, commits := range histories {
+		for _, commit := range commits {
+			if !seen[commit.Oid] {
+				seen[commit.Oid] = true
+				merged = append(merged, commit)
+			}
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Date > merged[j].Date
+	})
+	if limit > 0 && len(merged) > limit {
+		merged = merged[:limit]
+	}
+
+	return merged
+}
+
+// fetchCommitsInBranch pages through the history of a branch, newest first,
+// leaving out the commits reachable from any commit in stopAt. The history
+// is listed by date, so a merged branch older than the merge base comes after
+// it; the scan follows parents and only ends once every commit left is an
+// ancestor of a stop commit. A path limits the history to the commits that
+// touch it. That history skips the commits in between, so parents cannot be
+// followed and the scan stops at the first commit in stopAt instead; the
+// merge base may not touch the path, in which case the scan runs to the start
+// of the path's history. Every page is reported
+// to progress, together with the API budget left, and the commits read so far
+// are handed to onPage when it is set.
+func fetchCommitsInBranch(ctx context.Context, client models.GQLClient, owner, repo, branch string, limit int, stopAt []string, path string, associated bool, progress *loader.Reporter, onPage func(commits []Commit)) ([]Commit, error) {
+	stop := make(map[string]bool)
+	for _, oid := range stopAt {
+		stop[oid] = true
+	}
+	exclusion := utils.NewExclusion(stopAt)
+
 	var commits []Commit
 	var cursor *string
 	count := 0
@@ -43,72 +343,210 @@ func fetchCommitsInBranch(client models.GQLClient, owner, repo, branch string, l
 	for {
 		var query struct {
 			Repository struct {
-				Ref struct {
-					Target struct {
-						Commit struct {
-							History struct {
-								Edges []struct {
-									Node struct {
-										Oid     string
-										Message string
+				Object struct {
+					Commit struct {
+						History struct {
+							Edges []struct {
+								Node struct {
+									Oid           string
+									Message       string
+									CommittedDate string
+									URL           string `graphql:"url"`
+									Parents       struct {
+										TotalCount int
+										Nodes      []struct {
+											Oid string
+										}
+									} `graphql:"parents(first: 10)"`
+									Author struct {
+										Name string
+										User *struct {
+											Login string
+										}
 									}
+									AssociatedPullRequests struct {
+										Nodes []associatedPullRequest
+									} `graphql:"associatedPullRequests(first: 5) @include(if: $associated)"`
 								}
-								PageInfo models.PageInfo
-							} `graphql:"history(first: 100, after: $after)"`
-						} `graphql:"... on Commit"`
-					}
-				} `graphql:"ref(qualifiedName: $branch)"`
+							}
+							PageInfo models.PageInfo
+						} `graphql:"history(first: 100, after: $after, path: $path)"`
+					} `graphql:"... on Commit"`
+				} `graphql:"object(expression: $revision)"`
 			} `graphql:"repository(owner: $owner, name: $repo)"`
 		}
 
 		variables := map[string]any{
-			"owner":  graphql.String(owner),
-			"repo":   graphql.String(repo),
-			"branch": graphql.String(branch),
-			"after":  (*graphql.String)(cursor),
+			"owner":      graphql.String(owner),
+			"repo":       graphql.String(repo),
+			"revision":   graphql.String(utils.RemoteRevision(branch)),
+			"after":      (*graphql.String)(cursor),
+			"path":       pathVariable(path),
+			"associated": graphql.Boolean(associated),
 		}
 
-		if err := client.Query("CommitsInBranch", &query, variables); err != nil {
-			fmt.Fprintln(os.Stderr)
+		if err := client.Query(ctx, "CommitsInBranch", &query, variables); err != nil {
 			return nil, fmt.Errorf("failed to fetch commits for branch '%s': %w", branch, err)
 		}
 
-		if (query.Repository.Ref.Target.Commit.History.Edges == nil) || (len(query.Repository.Ref.Target.Commit.History.Edges) == 0 && query.Repository.Ref.Target.Commit.History.PageInfo.EndCursor == "") {
+		if (query.Repository.Object.Commit.History.Edges == nil) || (len(query.Repository.Object.Commit.History.Edges) == 0 && query.Repository.Object.Commit.History.PageInfo.EndCursor == "") {
 			break
 		}
 
-		edges := query.Repository.Ref.Target.Commit.History.Edges
+		edges := query.Repository.Object.Commit.History.Edges
 		if len(edges) == 0 {
 			break
 		}
 
+		pageStart := len(commits)
+		reachedEnd := false
 		for _, edge := range edges {
+			var parents []string
+			for _, parent := range edge.Node.Parents.Nodes {
+				parents = append(parents, parent.Oid)
+			}
+
+			keep := true
+			if path == "" {
+				keep = exclusion.Keep(edge.Node.Oid, parents)
+			} else if stop[edge.Node.Oid] {
+				reachedEnd = true
+				break
+			}
+			if !keep {
+				if exclusion.Done() {
+					reachedEnd = true
+					break
+				}
+				continue
+			}
+
+			author := edge.Node.Author.Name
+			if edge.Node.Author.User != nil {
+				author = edge.Node.Author.User.Login
+			}
+
 			commits = append(commits, Commit{
-				Oid:     edge.Node.Oid,
-				Message: edge.Node.Message,
+				Oid:          edge.Node.Oid,
+				Message:      edge.Node.Message,
+				Author:       author,
+				Date:         edge.Node.CommittedDate,
+				URL:          edge.Node.URL,
+				Parents:      edge.Node.Parents.TotalCount,
+				ParentOids:   parents,
+				AssociatedPR: pickAssociatedPR(edge.Node.Oid, edge.Node.AssociatedPullRequests.Nodes),
 			})
 			count++
-			if limit > 0 && count >= limit {
-				fmt.Fprintln(os.Stderr)
-				return commits, nil
+			if (limit > 0 && count >= limit) || exclusion.Done() {
+				reachedEnd = true
+				break
 			}
 		}
 
-		if !query.Repository.Ref.Target.Commit.History.PageInfo.HasNextPage {
+		progress.Page(len(commits)-pageStart, 0)
+		if remaining, ok := utils.RemainingBudget(client); ok {
+			progress.Budget(remaining)
+		}
+		if onPage != nil {
+			onPage(commits)
+		}
+
+		if reachedEnd || !query.Repository.Object.Commit.History.PageInfo.HasNextPage {
 			break
 		}
 
-		cursor = &query.Repository.Ref.Target.Commit.History.PageInfo.EndCursor
+		cursor = &query.Repository.Object.Commit.History.PageInfo.EndCursor
 	}
 
-	fmt.Fprintln(os.Stderr)
 	return commits, nil
 }
 
+func pathVariable(path string) *graphql.String {
+	if path == "" {
+		return nil
+	}
+
+	value := graphql.String(path)
+	return &value
+}
+
+// findMergeBase returns the commit both branches share, so that each side only
+// needs to be scanned back to it. API mode asks GitHub's compare endpoint so
+// the base matches the live history being paginated; local mode uses git.
+func findMergeBase(ctx context.Context, owner, repo, branchA, branchB string, isLocal bool) (string, error) {
+	if isLocal {
+		return utils.GetLocalMergeBase(branchA, branchB)
+	}
+
+	base, err := utils.GetMergeBaseFromAPI(ctx, owner, repo, utils.RemoteRevision(branchB), utils.RemoteRevision(branchA))
+	if err == nil {
+		return base, nil
+	}
+
+	if localBase, localErr := utils.GetLocalMergeBase(utils.RemoteTrackingRevision(branchA), utils.RemoteTrackingRevision(branchB)); localErr == nil {
+		return localBase, nil
+	}
+
+	return "", err
+}
+
+// findCommonBase extends findMergeBase to any number of branches by folding
+// the pairwise merge base through the remaining branches.
+func findCommonBase(ctx context.Context, owner, repo string, branches []string, isLocal bool) (string, error) {
+	if isLocal {
+		return utils.GetLocalMergeBase(branches...)
+	}
+
+	base := utils.RemoteRevision(branches[0])
+	for _, branch := range branches[1:] {
+		next, err := utils.GetMergeBaseFromAPI(ctx, owner, repo, base, utils.RemoteRevision(branch))
+		if err != nil {
+			var remoteRefs []string
+			for _, b := range branches {
+				remoteRefs = append(remoteRefs, utils.RemoteTrackingRevision(b))
+			}
+			if localBase, localErr := utils.GetLocalMergeBase(remoteRefs...); localErr == nil {
+				return localBase, nil
+			}
+
+			return "", err
+		}
+		base = next
+	}
+
+	return base, nil
+}
+
+// pickAssociatedPR chooses the merged PR that brought a commit in. A commit
+// can be associated with several PRs (e.g. an open PR that also contains it),
+// so the PR whose merge commit is this commit wins over other merged PRs.
+func pickAssociatedPR(oid string, candidates []associatedPullRequest) *models.PR {
+	var picked *associatedPullRequest
+	for i, candidate := range candidates {
+		if !candidate.Merged {
+			continue
+		}
+		if candidate.MergeCommit != nil && candidate.MergeCommit.Oid == oid {
+			picked = &candidates[i]
+			break
+		}
+		if picked == nil {
+			picked = &candidates[i]
+		}
+	}
+
+	if picked == nil {
+		return nil
+	}
+
+	pr := picked.toPR()
+	return &pr
+}
+
 func extractPRNumber(message string) (int, bool) {
-	matches := pullRequestRegex.FindStringSubmatch(message)
-	if len(matches) == 2 {
-		if num, err := strconv.Atoi(matches[1]); err == nil {
+	matches := pullRequestRegex.FindAllStringSubmatch(message, -1)
+	if len(matches) > 0 {
+		if num, err := strconv.Atoi(matches[len(matches)-1][1]); err == nil {
 			return num, true
 		}
 	}
@@ -0,0 +1,349 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/cli/shurcooL-graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractPRsFromCommits(t *testing.T) {
+	commits := []Commit{
+		{Oid: "c3", Message: "Fix: Bug (#102)\n\nSome details"},
+		{Oid: "c2", Message: "Direct push without a PR"},
+		{Oid: "c1", Message: "Feat: New API (#101)"},
+		{Oid: "c0", Message: "Feat: New API (#101)"},
+	}
+
+	extractors, _, err := resolveExtractors([]string{"squash"}, nil)
+	assert.NoError(t, err)
+
+	prs, _ := extractPRsFromCommits(commits, extractors)
+	assert.Len(t, prs, 2)
+	assert.Equal(t, 102, prs[0].Number)
+	assert.Equal(t, "Fix: Bug (#102)", prs[0].Title)
+	assert.Equal(t, 101, prs[1].Number)
+}
+
+func TestExtractPRNumber(t *testing.T) {
+	testCases := []struct {
+		name        string
+		message     string
+		expectedNum int
+		expectedOk  bool
+	}{
+		{name: "Squash title", message: "Feat: New API (#101)", expectedNum: 101, expectedOk: true},
+		{name: "Revert of a squash", message: `Revert "Feat: New API (#101)" (#105)`, expectedNum: 105, expectedOk: true},
+		{name: "No reference", message: "Direct push", expectedOk: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			num, ok := extractPRNumber(tc.message)
+			assert.Equal(t, tc.expectedOk, ok)
+			assert.Equal(t, tc.expectedNum, num)
+		})
+	}
+}
+
+func TestParseGitLog(t *testing.T) {
+	output := "aaa\x00ccc ddd\x00Jane Doe\x002024-05-01T10:00:00+02:00\x00Fix: Bug (#102)\n\nSome details\n\x1e\n" +
+		"bbb\x00ccc\x00John Doe\x002024-04-30T09:00:00+02:00\x00Feat: New API (#101)\n\x1e\n"
+
+	commits := parseGitLog(output)
+	assert.Len(t, commits, 2)
+	assert.Equal(t, "aaa", commits[0].Oid)
+	assert.Equal(t, 2, commits[0].Parents)
+	assert.Equal(t, "Jane Doe", commits[0].Author)
+	assert.Equal(t, "2024-05-01T10:00:00+02:00", commits[0].Date)
+	assert.Equal(t, "Fix: Bug (#102)\n\nSome details", commits[0].Message)
+	assert.Equal(t, "bbb", commits[1].Oid)
+	assert.Equal(t, "Feat: New API (#101)", commits[1].Message)
+}
+
+func TestParseGitLog_Empty(t *testing.T) {
+	assert.Empty(t, parseGitLog(""))
+}
+
+func TestFetchBranchCommits_PathMergedPR(t *testing.T) {
+	// GitHub's path-filtered history leaves out the merge commit of #7, so
+	// "wip a" only finds its PR through associatedPullRequests.
+	client := &mockGQLClient{pages: []string{`{"repository": {"object": {"commit": {"history": {
+		"edges": [{"node": {
+			"oid": "x1",
+			"message": "wip a",
+			"parents": {"totalCount": 1, "nodes": [{"oid": "c0"}]},
+			"associatedPullRequests": {"nodes": [{"number": 7, "title": "Touch a", "merged": true, "mergeCommit": {"oid": "m7"}}]}
+		}}],
+		"pageInfo": {"hasNextPage": false, "endCursor": "cursor"}
+	}}}}}`}}
+
+	commits, err := fetchBranchCommits(context.Background(), client, "my-org", "my-repo", "dev", 0, ScanOptions{Paths: []string{"svc/a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, graphql.Boolean(true), client.variables[0]["associated"])
+
+	prs, orphans := extractPRsFromCommits(commits, []PRExtractor{extractSquashReference, extractMergeReference})
+	assert.Len(t, prs, 1)
+	assert.Equal(t, 7, prs[0].Number)
+	assert.Empty(t, orphans)
+}
+
+func TestFetchCommitsForLocalBranch_PathMergedPR_Integration(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("skipping integration test: git is not installed")
+	}
+
+	dir := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(dir, path)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	git("init", "-q", "-b", "main")
+	write("svc/a/f", "0")
+	write("svc/b/f", "0")
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+	base := git("rev-parse", "HEAD")
+	git("checkout", "-q", "-b", "feat")
+	write("svc/a/f", "1")
+	git("commit", "-q", "-am", "wip a")
+	write("svc/b/f", "1")
+	git("commit", "-q", "-am", "other b")
+	git("checkout", "-q", "-b", "dev", "main")
+	write("svc/b/g", "x")
+	git("add", ".")
+	git("commit", "-q", "-m", "Unrelated b (#5)")
+	git("merge", "-q", "--no-ff", "feat", "-m", "Merge pull request #7 from org/feat", "-m", "Touch a")
+
+	t.Chdir(dir)
+
+	extractors := []PRExtractor{extractSquashReference, extractMergeReference}
+	records, err := FetchCommitsForLocalBranch(context.Background(), "dev", 0, ScanOptions{StopAt: []string{base}, Paths: []string{"svc/a"}, Extractors: extractors})
+	assert.NoError(t, err)
+
+	history := historyFromRecords(records, extractors)
+	assert.Len(t, history.PRs, 1)
+	assert.Equal(t, 7, history.PRs[0].Number)
+	assert.Equal(t, "Touch a", history.PRs[0].Title)
+	assert.Empty(t, history.Orphans)
+}
+
+func TestPickAssociatedPR(t *testing.T) {
+	mergeCommit := &struct{ Oid string }{Oid: "c1"}
+	candidates := []associatedPullRequest{
+		{pullRequestDetails: pullRequestDetails{Number: 7, Title: "Open PR that also contains the commit"}, Merged: false},
+		{pullRequestDetails: pullRequestDetails{Number: 8, Title: "Merged elsewhere"}, Merged: true, MergeCommit: &struct{ Oid string }{Oid: "other"}},
+		{pullRequestDetails: pullRequestDetails{Number: 9, Title: "Merged by this commit"}, Merged: true, MergeCommit: mergeCommit},
+	}
+
+	pr := pickAssociatedPR("c1", candidates)
+	assert.NotNil(t, pr)
+	assert.Equal(t, 9, pr.Number)
+
+	pr = pickAssociatedPR("c2", candidates)
+	assert.NotNil(t, pr)
+	assert.Equal(t, 8, pr.Number)
+
+	assert.Nil(t, pickAssociatedPR("c1", candidates[:1]))
+}
+
+func TestResolveCommitPR_FallsBackToMessage(t *testing.T) {
+	extractors, _, err := resolveExtractors([]string{"squash"}, nil)
+	assert.NoError(t, err)
+
+	commits := []Commit{
+		{Oid: "c2", Message: "Title edited after merge", AssociatedPR: &models.PR{Number: 12, Title: "Real title"}},
+		{Oid: "c1", Message: "Not associated (#11)"},
+	}
+
+	prs, _ := extractPRsFromCommits(commits, extractors)
+	assert.Len(t, prs, 2)
+	assert.Equal(t, models.PR{Number: 12, Title: "Real title", Oid: "c2"}, prs[0])
+	assert.Equal(t, 11, prs[1].Number)
+}
+
+func TestFetchCommitsInBranch(t *testing.T) {
+	t.Run("Paginates full history", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{
+			historyPage(true, "c4", "Four (#4)", "c3", "Three (#3)"),
+			historyPage(false, "c2", "Two (#2)", "c1", "One (#1)"),
+		}}
+
+		commits, err := fetchCommitsInBranch(context.Background(), client, "my-org", "my-repo", "dev", 0, nil, "", false, nil, nil)
+		assert.NoError(t, err)
+		assert.Len(t, commits, 4)
+		assert.Equal(t, 2, client.calls)
+	})
+
+	t.Run("Stops at the merge base", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{
+			historyPage(true, "c4", "Four (#4)", "c3", "Three (#3)"),
+			historyPage(true, "c2", "Two (#2)", "c1", "One (#1)"),
+		}}
+
+		commits, err := fetchCommitsInBranch(context.Background(), client, "my-org", "my-repo", "dev", 0, []string{"c3"}, "", false, nil, nil)
+		assert.NoError(t, err)
+		assert.Len(t, commits, 1)
+		assert.Equal(t, "c4", commits[0].Oid)
+		assert.Equal(t, 1, client.calls)
+	})
+
+	t.Run("Client returns an error", func(t *testing.T) {
+		client := &mockGQLClient{mockErr: fmt.Errorf("API rate limit exceeded")}
+
+		_, err := fetchCommitsInBranch(context.Background(), client, "my-org", "my-repo", "dev", 0, nil, "", false, nil, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "API rate limit exceeded")
+	})
+
+	t.Run("Keeps merged commits older than the merge base", func(t *testing.T) {
+		// dev has #1 and #2, main has #3, and main was merged into dev. The
+		// merge base #3 is newer than #1 and #2, so it is listed before them.
+		client := &mockGQLClient{pages: []string{
+			historyNodesPage(true,
+				historyNode{oid: "m1", message: "Merge branch 'main' into dev", parents: []string{"c2", "c3"}},
+				historyNode{oid: "c3", message: "Three (#3)", parents: []string{"c0"}},
+			),
+			historyNodesPage(true,
+				historyNode{oid: "c2", message: "Two (#2)", parents: []string{"c1"}},
+				historyNode{oid: "c1", message: "One (#1)", parents: []string{"c0"}},
+			),
+			historyNodesPage(true,
+				historyNode{oid: "c0", message: "Initial commit"},
+			),
+		}}
+
+		commits, err := fetchCommitsInBranch(context.Background(), client, "my-org", "my-repo", "dev", 0, []string{"c3"}, "", false, nil, nil)
+		assert.NoError(t, err)
+
+		var oids []string
+		for _, commit := range commits {
+			oids = append(oids, commit.Oid)
+		}
+		assert.Equal(t, []string{"m1", "c2", "c1"}, oids)
+		assert.Equal(t, []string{"c2", "c3"}, commits[0].ParentOids)
+		assert.Equal(t, 2, client.calls, "c0 is known to be an ancestor of the merge base, so its page is not fetched")
+	})
+
+	t.Run("Reports every page", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{
+			historyNodesPage(true,
+				historyNode{oid: "c4", message: "Four (#4)", parents: []string{"c3"}},
+				historyNode{oid: "c3", message: "Three (#3)", parents: []string{"c2"}},
+			),
+			historyPage(false, "c2", "Two (#2)", "c1", "One (#1)"),
+		}}
+		progress := make(chan loader.Progress, 8)
+
+		_, err := fetchCommitsInBranch(context.Background(), client, "my-org", "my-repo", "dev", 0, []string{"c1"}, "", false, loader.NewReporter(context.Background(), progress, "dev"), nil)
+		assert.NoError(t, err)
+		assert.Len(t, progress, 2)
+		<-progress
+		assert.Equal(t, loader.Progress{Label: "dev", Pages: 2, Commits: 3}, <-progress)
+	})
+
+	t.Run("Hands every page to onPage", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{
+			historyPage(true, "c4", "Four (#4)", "c3", "Three (#3)"),
+			historyPage(false, "c2", "Two (#2)", "c1", "One (#1)"),
+		}}
+
+		var pages []int
+		_, err := fetchCommitsInBranch(context.Background(), client, "my-org", "my-repo", "dev", 0, nil, "", false, nil, func(commits []Commit) {
+			pages = append(pages, len(commits))
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []int{2, 4}, pages)
+	})
+
+	t.Run("Stops when the context is cancelled", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{historyPage(false, "c1", "One (#1)")}}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := FetchCommitsForBranch(ctx, client, "my-org", "my-repo", "dev", 0, ScanOptions{})
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 0, client.calls)
+	})
+}
+
+func TestMergeHistories(t *testing.T) {
+	histories := [][]Commit{
+		{{Oid: "c4", Date: "2024-03-04T00:00:00Z"}, {Oid: "c2", Date: "2024-03-02T00:00:00Z"}},
+		{{Oid: "c3", Date: "2024-03-03T00:00:00Z"}, {Oid: "c2", Date: "2024-03-02T00:00:00Z"}, {Oid: "c1", Date: "2024-03-01T00:00:00Z"}},
+	}
+
+	var oids []string
+	for _, commit := range mergeHistories(histories, 0) {
+		oids = append(oids, commit.Oid)
+	}
+	assert.Equal(t, []string{"c4", "c3", "c2", "c1"}, oids)
+
+	assert.Len(t, mergeHistories(histories, 2), 2)
+}
+
+func TestExtractPRsFromCommits_MergedBranchCommits(t *testing.T) {
+	extractors, _, err := resolveExtractors([]string{"squash", "merge"}, nil)
+	assert.NoError(t, err)
+
+	commits := []Commit{
+		{Oid: "m7", Message: "Merge pull request #7 from org/parser\n\nAdd parser", Parents: 2, ParentOids: []string{"c2", "f2"}},
+		{Oid: "f2", Message: "address review", Parents: 1, ParentOids: []string{"f1"}},
+		{Oid: "c2", Message: "Hotfix typo", Parents: 1, ParentOids: []string{"c1"}},
+		{Oid: "f1", Message: "wip: add parser", Parents: 1, ParentOids: []string{"c1"}},
+		{Oid: "c1", Message: "Direct push", Parents: 1},
+	}
+
+	prs, orphans := extractPRsFromCommits(commits, extractors)
+	assert.Len(t, prs, 1)
+	assert.Equal(t, 7, prs[0].Number)
+
+	var subjects []string
+	for _, orphan := range orphans {
+		subjects = append(subjects, orphan.Subject)
+	}
+	assert.Equal(t, []string{"Hotfix typo", "Direct push"}, subjects)
+}
+
+func TestExtractPRsFromCommits_Orphans(t *testing.T) {
+	extractors, _, err := resolveExtractors([]string{"squash", "merge"}, nil)
+	assert.NoError(t, err)
+
+	commits := []Commit{
+		{Oid: "c5", Message: "Merge branch 'main' into dev", Parents: 2},
+		{Oid: "c4", Message: "Revert \"Bump version\"\n\nThis reverts commit c2.", Author: "jane", Parents: 1},
+		{Oid: "c3", Message: "Fix: Bug (#102)", Parents: 1},
+		{Oid: "c2", Message: "Bump version", Author: "john", Date: "2024-05-01T10:00:00Z", Parents: 1},
+		{Oid: "c1", Message: "Hotfix typo\n\nPushed directly", Author: "john", Parents: 1},
+	}
+
+	prs, orphans := extractPRsFromCommits(commits, extractors)
+	assert.Len(t, prs, 1)
+	assert.Equal(t, 102, prs[0].Number)
+
+	assert.Len(t, orphans, 3)
+	assert.Equal(t, models.Commit{Oid: "c4", Subject: `Revert "Bump version"`, Author: "jane"}, orphans[0])
+	assert.Equal(t, models.Commit{Oid: "c2", Subject: "Bump version", Author: "john", Date: "2024-05-01T10:00:00Z", RevertedBy: "c4"}, orphans[1])
+	assert.Equal(t, "Hotfix typo", orphans[2].Subject)
+}
@@ -0,0 +1,93 @@
+package prs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+type mockGQLClient struct {
+	pages   []string
+	mockErr error
+	// pageErr is returned together with the decoded page, as GitHub does
+	// for partial results.
+	pageErr error
+	calls   int
+	// variables holds the variables of every query, in order.
+	variables []map[string]any
+}
+
+func (m *mockGQLClient) Query(ctx context.Context, queryName string, response any, variables map[string]any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if m.mockErr != nil {
+		return m.mockErr
+	}
+	if m.calls >= len(m.pages) {
+		return fmt.Errorf("unexpected call %d for query %s", m.calls+1, queryName)
+	}
+	page := m.pages[m.calls]
+	m.calls++
+	m.variables = append(m.variables, variables)
+
+	if err := json.Unmarshal([]byte(page), response); err != nil {
+		return err
+	}
+
+	return m.pageErr
+}
+
+type historyNode struct {
+	oid     string
+	message string
+	parents []string
+}
+
+// historyPage is a page of linear history: every commit's parent is the one
+// listed after it, and the last commit's parent is left out.
+func historyPage(hasNextPage bool, messagesByOid ...string) string {
+	var nodes []historyNode
+	for i := 0; i+1 < len(messagesByOid); i += 2 {
+		node := historyNode{oid: messagesByOid[i], message: messagesByOid[i+1]}
+		if i+3 < len(messagesByOid) {
+			node.parents = []string{messagesByOid[i+2]}
+		}
+		nodes = append(nodes, node)
+	}
+
+	return historyNodesPage(hasNextPage, nodes...)
+}
+
+func historyNodesPage(hasNextPage bool, nodes ...historyNode) string {
+	var edges []map[string]any
+	for _, node := range nodes {
+		var parents []map[string]any
+		for _, parent := range node.parents {
+			parents = append(parents, map[string]any{"oid": parent})
+		}
+		edges = append(edges, map[string]any{
+			"node": map[string]any{
+				"oid":     node.oid,
+				"message": node.message,
+				"parents": map[string]any{"totalCount": len(parents), "nodes": parents},
+			},
+		})
+	}
+
+	page := map[string]any{
+		"repository": map[string]any{
+			"object": map[string]any{
+				"commit": map[string]any{
+					"history": map[string]any{
+						"edges":    edges,
+						"pageInfo": map[string]any{"hasNextPage": hasNextPage, "endCursor": "cursor"},
+					},
+				},
+			},
+		},
+	}
+
+	content, _ := json.Marshal(page)
+	return string(content)
+}
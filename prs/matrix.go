@@ -0,0 +1,151 @@
+package prs
+
+import (
+	"context"
+	"os"
+
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/output"
+	"github.com/astein-peddi/git-tooling/utils"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var matrixJSONFields = []string{
+	"number", "title", "author", "labels", "mergedAt", "url", "baseRef", "closingIssues",
+	"oid", "branches", "skipped",
+}
+
+type matrixResult struct {
+	rows      []MatrixRow
+	snapshots []branchSnapshot
+}
+
+func setupMatrixCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "matrix <branch> <branch> [branch...]",
+		Short:             "Show which PRs are present in each branch of a promotion chain",
+		Long:              "Show one row per PR and one column per branch. Branches are given in promotion order (e.g. dev rtm main), and PRs that skipped a stage are highlighted.",
+		Args:              cobra.MinimumNArgs(2),
+		ValidArgsFunction: completeBranches,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			exporter, err := output.NewJSONExporter(cmd, matrixJSONFields)
+			if err != nil {
+				return err
+			}
+
+			config, err := newScanConfig(cmd, args...)
+			if err != nil {
+				return err
+			}
+
+			task := func(ctx context.Context, progress chan<- loader.Progress) (any, error) {
+				client, err := config.client()
+				if err != nil {
+					return nil, err
+				}
+
+				snapshots, err := config.resolveSnapshots(ctx, client, args)
+				if err != nil {
+					return nil, err
+				}
+
+				var since string
+				if !config.fullHistory {
+					if base, err := findCommonBase(ctx, config.owner, config.repo, args, config.isLocal); err == nil {
+						since = base
+					}
+				}
+
+				results, err := config.scanBranches(ctx, progress, client, snapshots, since, nil)
+				if err != nil {
+					return nil, err
+				}
+
+				prsByBranch := make(map[string][]models.PR)
+				for branch, history := range results {
+					prsByBranch[branch] = history.PRs
+				}
+
+				rows := config.filter.applyMatrix(buildMatrix(args, prsByBranch, config.includeReverted))
+
+				return matrixResult{rows: rows, snapshots: snapshots}, nil
+			}
+
+			result, err := loader.Run(cmd.Context(), "Scanning branch histories", task)
+			if err != nil {
+				return err
+			}
+
+			matrix := result.(matrixResult)
+			rows := matrix.rows
+
+			if exporter != nil {
+				printSnapshot(matrix.snapshots)
+				selected, err := exporter.Select(rows)
+				if err != nil {
+					return err
+				}
+
+				return exporter.Write(os.Stdout, selected)
+			}
+
+			if !utils.IsInteractive() {
+				printSnapshot(matrix.snapshots)
+
+				return output.Render(os.Stdout, "table", matrixTable(args, rows))
+			}
+
+			p := tea.NewProgram(initialMatrixModel(args, rows, matrix.snapshots), tea.WithAltScreen())
+			_, err = p.Run()
+
+			return err
+		},
+	}
+
+	output.AddJSONFlags(cmd, false, matrixJSONFields)
+
+	return cmd
+}
+
+// buildMatrix lists every PR found in any branch, in the order the branches
+// were given. A PR skipped a stage when it is present in a later branch but
+// missing from an earlier one. Unless includeReverted is set, a PR reverted
+// within a branch counts as absent from it.
+func buildMatrix(branches []string, results map[string][]models.PR, includeReverted bool) []MatrixRow {
+	var rows []MatrixRow
+	index := make(map[int]int)
+
+	for _, branch := range branches {
+		for _, pr := range results[branch] {
+			if pr.RevertedBy != "" && !includeReverted {
+				continue
+			}
+			i, ok := index[pr.Number]
+			if !ok {
+				rows = append(rows, MatrixRow{PR: pr, Branches: make(map[string]bool)})
+				i = len(rows) - 1
+				index[pr.Number] = i
+			}
+			rows[i].Branches[branch] = true
+		}
+	}
+
+	for i := range rows {
+		for j, branch := range branches {
+			if rows[i].Branches[branch] {
+				continue
+			}
+			rows[i].Branches[branch] = false
+			for _, later := range branches[j+1:] {
+				if rows[i].Branches[later] {
+					rows[i].Skipped = append(rows[i].Skipped, branch)
+					break
+				}
+			}
+		}
+	}
+
+	return rows
+}
@@ -0,0 +1,31 @@
+package prs
+
+import (
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMatrix(t *testing.T) {
+	branches := []string{"dev", "rtm", "main"}
+	results := map[string][]models.PR{
+		"dev":  {{Number: 103, Title: "In dev only"}, {Number: 101, Title: "Promoted everywhere"}},
+		"rtm":  {{Number: 101, Title: "Promoted everywhere"}},
+		"main": {{Number: 102, Title: "Hotfix straight to main"}, {Number: 101, Title: "Promoted everywhere"}},
+	}
+
+	rows := buildMatrix(branches, results, false)
+	assert.Len(t, rows, 3)
+
+	assert.Equal(t, 103, rows[0].Number)
+	assert.Equal(t, map[string]bool{"dev": true, "rtm": false, "main": false}, rows[0].Branches)
+	assert.Empty(t, rows[0].Skipped)
+
+	assert.Equal(t, 101, rows[1].Number)
+	assert.Equal(t, map[string]bool{"dev": true, "rtm": true, "main": true}, rows[1].Branches)
+	assert.Empty(t, rows[1].Skipped)
+
+	assert.Equal(t, 102, rows[2].Number)
+	assert.Equal(t, []string{"dev", "rtm"}, rows[2].Skipped)
+}
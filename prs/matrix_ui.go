@@ -0,0 +1,138 @@
+package prs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/astein-peddi/git-tooling/theme"
+	"github.com/charmbracelet/bubbles/table"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type matrixModel struct {
+	branches  []string
+	rows      []MatrixRow
+	snapshots []branchSnapshot
+	table     table.Model
+}
+
+func initialMatrixModel(branches []string, rows []MatrixRow, snapshots []branchSnapshot) matrixModel {
+	return matrixModel{
+		branches:  branches,
+		rows:      rows,
+		snapshots: snapshots,
+	}
+}
+
+func (m matrixModel) Init() tea.Cmd {
+	return tea.WindowSize()
+}
+
+func (m matrixModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+		case tea.WindowSizeMsg:
+			m.table = setupMatrixTable(msg.Width, m.branches, m.rows)
+			return m, nil
+
+		case tea.KeyMsg:
+			switch msg.String() {
+				case "q", "ctrl+c":
+					return m, tea.Quit
+
+				case "enter":
+					cursor := m.table.Cursor()
+					if cursor < 0 || cursor >= len(m.rows) || m.rows[cursor].URL == "" {
+						return m, nil
+					}
+
+					return m, openURLCmd(m.rows[cursor].URL)
+			}
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m matrixModel) View() string {
+	if m.table.Columns() == nil {
+		return "Initializing..."
+	}
+
+	header := fmt.Sprintf("PR presence across %s\n", strings.Join(m.branches, " → ")) + snapshotHeader(m.snapshots)
+
+	skipped := 0
+	for _, row := range m.rows {
+		if len(row.Skipped) > 0 {
+			skipped++
+		}
+	}
+
+	var body, footer string
+	if len(m.rows) > 0 {
+		body = m.table.View()
+		footer = fmt.Sprintf("\n\n(↑/↓ to move or Vim Motions, Enter to open, q to quit)  %d/%d", m.table.Cursor()+1, len(m.rows))
+	} else {
+		body = "No PRs found."
+		footer = "\n\n(q to quit)"
+	}
+
+	if skipped > 0 {
+		header += theme.DefaultTheme.Warning.Render(fmt.Sprintf("%d PR(s) skipped a stage", skipped)) + "\n\n"
+	}
+
+	return lipgloss.NewStyle().Margin(1, 2).Render(
+		header +
+		body +
+		theme.DefaultTheme.MutedText.Render(footer),
+	)
+}
+
+func setupMatrixTable(termWidth int, branches []string, rows []MatrixRow) table.Model {
+	numWidth := 8
+	branchWidth := 10
+	skippedWidth := 20
+	padding := 6 + 2*len(branches)
+	titleWidth := max(termWidth - numWidth - branchWidth*len(branches) - skippedWidth - padding, 20)
+
+	columns := []table.Column{
+		{Title: "Number", Width: numWidth},
+		{Title: "Title", Width: titleWidth},
+	}
+	for _, branch := range branches {
+		columns = append(columns, table.Column{Title: branch, Width: branchWidth})
+	}
+	columns = append(columns, table.Column{Title: "Skipped", Width: skippedWidth})
+
+	tbl := table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(20),
+	)
+
+	styles := table.DefaultStyles()
+	styles.Header = theme.DefaultTheme.TableHeader
+	styles.Selected = theme.DefaultTheme.SelectedListItem
+	tbl.SetStyles(styles)
+
+	tableRows := []table.Row{}
+	for _, row := range rows {
+		cells := table.Row{fmt.Sprintf("#%d", row.Number), row.Title}
+		for _, branch := range branches {
+			presence := "✗"
+			if row.Branches[branch] {
+				presence = "✓"
+			}
+			cells = append(cells, presence)
+		}
+
+		skipped := ""
+		if len(row.Skipped) > 0 {
+			skipped = theme.DefaultTheme.Warning.Render("⚠ " + strings.Join(row.Skipped, ", "))
+		}
+		tableRows = append(tableRows, append(cells, skipped))
+	}
+	tbl.SetRows(tableRows)
+	return tbl
+}
@@ -1,14 +1,103 @@
 package prs
 
-import "github.com/astein-peddi/git-tooling/models"
+import (
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/astein-peddi/git-tooling/models"
+)
 
 type Commit struct {
-	Oid     string
-	Message string
+	Oid          string
+	Message      string
+	Author       string
+	Date         string
+	URL          string
+	Parents      int
+	ParentOids   []string
+	AssociatedPR *models.PR
+}
+
+type pullRequestDetails struct {
+	Number      int
+	Title       string
+	URL         string `graphql:"url"`
+	MergedAt    *string
+	BaseRefName string
+	Author      *struct {
+		Login string
+	}
+	Labels struct {
+		Nodes []struct {
+			Name string
+		}
+	} `graphql:"labels(first: 20)"`
+	ClosingIssuesReferences struct {
+		Nodes []struct {
+			Number int
+		}
+	} `graphql:"closingIssuesReferences(first: 10)"`
+}
+
+type associatedPullRequest struct {
+	pullRequestDetails
+	Merged      bool
+	MergeCommit *struct {
+		Oid string
+	}
+}
+
+type ScanOptions struct {
+	StopAt     []string
+	Associated bool
+	Extractors []PRExtractor
+	Paths      []string
+	Revision   string
+	Progress   *loader.Reporter
+	// OnPage, when set, receives the commits read so far after every page,
+	// newest first.
+	OnPage func(commits []Commit)
+}
+
+const (
+	statusPending    = "pending"
+	statusBackported = "backported"
+	statusReverted   = "reverted"
+)
+
+const (
+	directionForward = "forward"
+	directionReverse = "reverse"
+)
+
+type ComparedPR struct {
+	models.PR
+	Status       string `json:"status"`
+	Direction    string `json:"direction"`
+	BackportedAs int    `json:"backportedAs,omitempty"`
+}
+
+type ComparedCommit struct {
+	models.Commit
+	Status       string `json:"status"`
+	Direction    string `json:"direction"`
+	BackportedAs string `json:"backportedAs,omitempty"`
+}
+
+type comparison struct {
+	prs       []ComparedPR
+	commits   []ComparedCommit
+	snapshots []branchSnapshot
+	// unfiltered marks a preview that skipped the filters needing PR
+	// details, which are only loaded once a branch is scanned.
+	unfiltered bool
 }
 
 type branchScanResult struct {
 	branchName string
-	prs        []models.PR
+	history    models.BranchHistory
 	err        error
-}
\ No newline at end of file
+}
+type MatrixRow struct {
+	models.PR
+	Branches map[string]bool `json:"branches"`
+	Skipped  []string        `json:"skipped,omitempty"`
+}
@@ -0,0 +1,228 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/astein-peddi/git-tooling/output"
+	"github.com/spf13/cobra"
+)
+
+const otherGroupTitle = "Other"
+
+var (
+	conventionalTitleRegex = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?!?:\s*(.+)$`)
+	squashSuffixRegex      = regexp.MustCompile(`\s*\(#\d+\)$`)
+)
+
+var conventionalTypes = []struct {
+	name  string
+	title string
+}{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance"},
+	{"refactor", "Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build"},
+	{"ci", "CI"},
+	{"style", "Style"},
+	{"chore", "Chores"},
+	{"revert", "Reverts"},
+}
+
+const defaultReleaseNotesTemplate = `# Release notes: {{.From}} → {{.To}}
+{{range .Groups}}
+## {{.Title}}
+
+{{range .Notes}}- {{if .Scope}}**{{.Scope}}:** {{end}}{{.Summary}} ({{if .URL}}[#{{.Number}}]({{.URL}}){{else}}#{{.Number}}{{end}}){{if .Credit}} by {{.Credit}}{{end}}
+{{end}}{{end}}{{if .Contributors}}
+## Contributors
+
+{{range .Contributors}}- {{.}}
+{{end}}{{end}}`
+
+type releaseNote struct {
+	ComparedPR
+	Type    string
+	Scope   string
+	Summary string
+	Credit  string
+}
+
+type releaseNoteGroup struct {
+	Title string
+	Notes []releaseNote
+}
+
+type releaseNotesData struct {
+	From         string
+	To           string
+	Groups       []releaseNoteGroup
+	Notes        []releaseNote
+	Contributors []string
+}
+
+func setupReleaseNotesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "release-notes <from> <to>",
+		Short:             "Render Markdown release notes for PRs in <from> that are not yet in <to>",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeBranches,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from := args[0]
+			to := args[1]
+			groupBy, _ := cmd.Flags().GetString("group-by")
+			templateText, _ := cmd.Flags().GetString("template")
+
+			if groupBy != "type" && groupBy != "label" {
+				return fmt.Errorf("invalid --group-by '%s': must be 'type' or 'label'", groupBy)
+			}
+
+			tmpl, err := loadReleaseNotesTemplate(templateText)
+			if err != nil {
+				return err
+			}
+
+			config, err := newScanConfig(cmd, from, to)
+			if err != nil {
+				return err
+			}
+
+			task := func(ctx context.Context, progress chan<- loader.Progress) (any, error) {
+				return config.compareBranches(ctx, progress, from, to, false, nil)
+			}
+
+			result, err := loader.Run(cmd.Context(), "Scanning branch histories", task)
+			if err != nil {
+				return err
+			}
+
+			compared := result.(comparison)
+			printSnapshot(compared.snapshots)
+
+			data := buildReleaseNotes(from, to, compared.prs, groupBy, config)
+			if err := tmpl.Execute(os.Stdout, data); err != nil {
+				return fmt.Errorf("failed to render release notes: %w", err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("group-by", "type", "Group PRs by conventional-commit 'type' or by first 'label'")
+	cmd.Flags().String("template", "", "Render the notes with a Go template (inline, or a path to a template file)")
+
+	return cmd
+}
+
+// loadReleaseNotesTemplate reads --template the same way prs --template
+// does, falling back to the default notes layout when it is not set.
+func loadReleaseNotesTemplate(value string) (*template.Template, error) {
+	if value == "" {
+		value = defaultReleaseNotesTemplate
+	}
+
+	return output.LoadTemplate(value)
+}
+
+func buildReleaseNotes(from, to string, prs []ComparedPR, groupBy string, config scanConfig) releaseNotesData {
+	data := releaseNotesData{From: from, To: to}
+
+	groups := make(map[string][]releaseNote)
+	seenContributors := make(map[string]bool)
+	for _, pr := range prs {
+		if pr.Status != statusPending {
+			continue
+		}
+
+		note := newReleaseNote(pr, config)
+		data.Notes = append(data.Notes, note)
+
+		groupTitle := otherGroupTitle
+		if groupBy == "label" && len(pr.Labels) > 0 {
+			groupTitle = pr.Labels[0]
+		} else if groupBy == "type" {
+			groupTitle = conventionalTypeTitle(note.Type)
+		}
+		groups[groupTitle] = append(groups[groupTitle], note)
+
+		if note.Credit != "" && !seenContributors[note.Credit] {
+			seenContributors[note.Credit] = true
+			data.Contributors = append(data.Contributors, note.Credit)
+		}
+	}
+
+	for _, title := range orderedGroupTitles(groups, groupBy) {
+		data.Groups = append(data.Groups, releaseNoteGroup{Title: title, Notes: groups[title]})
+	}
+	sort.Strings(data.Contributors)
+
+	return data
+}
+
+func newReleaseNote(pr ComparedPR, config scanConfig) releaseNote {
+	note := releaseNote{ComparedPR: pr}
+
+	title := squashSuffixRegex.ReplaceAllString(pr.Title, "")
+	note.Summary = title
+	if matches := conventionalTitleRegex.FindStringSubmatch(title); matches != nil {
+		note.Type = strings.ToLower(matches[1])
+		note.Scope = matches[2]
+		note.Summary = matches[3]
+	}
+
+	if note.URL == "" && !config.isLocal {
+		note.URL = fmt.Sprintf("https://github.com/%s/%s/pull/%d", config.owner, config.repo, pr.Number)
+	}
+
+	if pr.Author != "" {
+		note.Credit = pr.Author
+		if !config.isLocal {
+			note.Credit = "@" + pr.Author
+		}
+	}
+
+	return note
+}
+
+func conventionalTypeTitle(commitType string) string {
+	for _, t := range conventionalTypes {
+		if t.name == commitType {
+			return t.title
+		}
+	}
+
+	return otherGroupTitle
+}
+
+func orderedGroupTitles(groups map[string][]releaseNote, groupBy string) []string {
+	var titles []string
+	if groupBy == "type" {
+		for _, t := range conventionalTypes {
+			if _, ok := groups[t.title]; ok {
+				titles = append(titles, t.title)
+			}
+		}
+	} else {
+		for title := range groups {
+			if title != otherGroupTitle {
+				titles = append(titles, title)
+			}
+		}
+		sort.Strings(titles)
+	}
+
+	if _, ok := groups[otherGroupTitle]; ok {
+		titles = append(titles, otherGroupTitle)
+	}
+
+	return titles
+}
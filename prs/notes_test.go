@@ -0,0 +1,98 @@
+package prs
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestComparedPR(number int, title, author string, labels ...string) ComparedPR {
+	return ComparedPR{
+		PR:     models.PR{Number: number, Title: title, Author: author, Labels: labels},
+		Status: statusPending,
+	}
+}
+
+func TestBuildReleaseNotes(t *testing.T) {
+	config := scanConfig{owner: "my-org", repo: "my-repo"}
+	prs := []ComparedPR{
+		newTestComparedPR(103, "fix(ui): Crash on start (#103)", "jane", "bug"),
+		newTestComparedPR(102, "Update dependencies", "john"),
+		newTestComparedPR(101, "feat: New API (#101)", "jane", "feature"),
+		{PR: models.PR{Number: 100, Title: "fix: Hotfix"}, Status: statusBackported, BackportedAs: 99},
+	}
+
+	t.Run("Group by conventional type", func(t *testing.T) {
+		data := buildReleaseNotes("dev", "main", prs, "type", config)
+
+		assert.Len(t, data.Notes, 3)
+		assert.Len(t, data.Groups, 3)
+		assert.Equal(t, "Features", data.Groups[0].Title)
+		assert.Equal(t, "Bug Fixes", data.Groups[1].Title)
+		assert.Equal(t, "Other", data.Groups[2].Title)
+
+		fix := data.Groups[1].Notes[0]
+		assert.Equal(t, "ui", fix.Scope)
+		assert.Equal(t, "Crash on start", fix.Summary)
+		assert.Equal(t, "https://github.com/my-org/my-repo/pull/103", fix.URL)
+		assert.Equal(t, []string{"@jane", "@john"}, data.Contributors)
+	})
+
+	t.Run("Group by label", func(t *testing.T) {
+		data := buildReleaseNotes("dev", "main", prs, "label", config)
+
+		assert.Len(t, data.Groups, 3)
+		assert.Equal(t, "bug", data.Groups[0].Title)
+		assert.Equal(t, "feature", data.Groups[1].Title)
+		assert.Equal(t, "Other", data.Groups[2].Title)
+	})
+
+	t.Run("Local mode credits commit authors without a handle", func(t *testing.T) {
+		data := buildReleaseNotes("dev", "main", prs[:1], "type", scanConfig{isLocal: true})
+
+		assert.Equal(t, "jane", data.Notes[0].Credit)
+		assert.Empty(t, data.Notes[0].URL)
+	})
+}
+
+func TestDefaultReleaseNotesTemplate(t *testing.T) {
+	tmpl, err := loadReleaseNotesTemplate("")
+	assert.NoError(t, err)
+
+	data := buildReleaseNotes("dev", "main", []ComparedPR{newTestComparedPR(101, "feat: New API (#101)", "jane")}, "type", scanConfig{owner: "my-org", repo: "my-repo"})
+
+	var out bytes.Buffer
+	assert.NoError(t, tmpl.Execute(&out, data))
+	assert.Contains(t, out.String(), "## Features")
+	assert.Contains(t, out.String(), "- New API ([#101](https://github.com/my-org/my-repo/pull/101)) by @jane")
+	assert.Contains(t, out.String(), "## Contributors")
+}
+
+func TestReleaseNotesTemplate(t *testing.T) {
+	data := buildReleaseNotes("dev", "main", []ComparedPR{newTestComparedPR(101, "feat: New API (#101)", "jane")}, "type", scanConfig{owner: "my-org", repo: "my-repo"})
+
+	t.Run("Inline text", func(t *testing.T) {
+		tmpl, err := loadReleaseNotesTemplate(`{{range .Notes}}{{.Number}} {{.Summary}}{{end}}`)
+		assert.NoError(t, err)
+
+		var out bytes.Buffer
+		assert.NoError(t, tmpl.Execute(&out, data))
+		assert.Equal(t, "101 New API", out.String())
+	})
+
+	t.Run("Template file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "notes.tmpl")
+		assert.NoError(t, os.WriteFile(path, []byte(`{{.From}}..{{.To}}: {{len .Notes}}`), 0644))
+
+		tmpl, err := loadReleaseNotesTemplate(path)
+		assert.NoError(t, err)
+
+		var out bytes.Buffer
+		assert.NoError(t, tmpl.Execute(&out, data))
+		assert.Equal(t, "dev..main: 1", out.String())
+	})
+}
@@ -0,0 +1,91 @@
+package prs
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	revertSubjectRegex = regexp.MustCompile(`^Revert "(.+)"`)
+	revertTrailerRegex = regexp.MustCompile(`(?m)This reverts commit ([0-9a-fA-F]{7,40})`)
+	revertsPRRegex     = regexp.MustCompile(`(?m)^Reverts [\w.-]+/[\w.-]+#(\d+)\s*$`)
+)
+
+type revertTarget struct {
+	oid      string
+	prNumber int
+	title    string
+}
+
+// parseRevert recognises `git revert` commits through their "This reverts
+// commit <sha>" trailer, and GitHub revert PRs through their `Revert "..."`
+// subject and "Reverts owner/repo#N" body line.
+func parseRevert(message string) (revertTarget, bool) {
+	var target revertTarget
+	found := false
+
+	if matches := revertTrailerRegex.FindStringSubmatch(message); matches != nil {
+		target.oid = strings.ToLower(matches[1])
+		found = true
+	}
+
+	if matches := revertsPRRegex.FindStringSubmatch(message); matches != nil {
+		if num, err := strconv.Atoi(matches[1]); err == nil {
+			target.prNumber = num
+			found = true
+		}
+	}
+
+	if matches := revertSubjectRegex.FindStringSubmatch(commitSubject(message)); matches != nil {
+		target.title = matches[1]
+		// A revert of a revert names the revert commit, not the original PR.
+		if target.prNumber == 0 && !revertSubjectRegex.MatchString(target.title) {
+			if num, ok := extractPRNumber(target.title); ok {
+				target.prNumber = num
+			}
+		}
+		found = true
+	}
+
+	return target, found
+}
+
+// detectReverts maps the index of every reverted commit to the index of the
+// commit that reverted it. Commits are ordered newest first, so a revert that
+// was itself reverted later is seen first and its own revert is ignored,
+// which keeps re-applied PRs pending.
+func detectReverts(commits []Commit, resolved []resolvedCommit) map[int]int {
+	revertedBy := make(map[int]int)
+
+	for i, commit := range commits {
+		if _, undone := revertedBy[i]; undone {
+			continue
+		}
+
+		target, ok := parseRevert(commit.Message)
+		if !ok {
+			continue
+		}
+
+		for j := i + 1; j < len(commits); j++ {
+			if target.matches(commits[j], resolved[j]) {
+				revertedBy[j] = i
+				break
+			}
+		}
+	}
+
+	return revertedBy
+}
+
+func (t revertTarget) matches(commit Commit, resolved resolvedCommit) bool {
+	if t.oid != "" {
+		return strings.HasPrefix(strings.ToLower(commit.Oid), t.oid)
+	}
+	if t.prNumber != 0 {
+		return resolved.ok && resolved.pr.Number == t.prNumber
+	}
+
+	return t.title != "" && commitSubject(commit.Message) == t.title
+}
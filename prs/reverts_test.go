@@ -0,0 +1,83 @@
+package prs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRevert(t *testing.T) {
+	testCases := []struct {
+		name     string
+		message  string
+		expected revertTarget
+		ok       bool
+	}{
+		{
+			name:     "git revert trailer",
+			message:  "Revert \"Feat: New API (#101)\"\n\nThis reverts commit 0123456789abcdef0123456789abcdef01234567.",
+			expected: revertTarget{oid: "0123456789abcdef0123456789abcdef01234567", prNumber: 101, title: "Feat: New API (#101)"},
+			ok:       true,
+		},
+		{
+			name:     "GitHub revert PR",
+			message:  "Revert \"Feat: New API (#101)\" (#105)\n\nReverts my-org/my-repo#101",
+			expected: revertTarget{prNumber: 101, title: "Feat: New API (#101)"},
+			ok:       true,
+		},
+		{
+			name:     "Revert of a revert",
+			message:  "Revert \"Revert \"Feat: New API (#101)\"\"",
+			expected: revertTarget{title: "Revert \"Feat: New API (#101)\""},
+			ok:       true,
+		},
+		{name: "Not a revert", message: "Feat: New API (#101)", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			target, ok := parseRevert(tc.message)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, target)
+		})
+	}
+}
+
+func TestExtractPRsFromCommits_Reverts(t *testing.T) {
+	extractors, _, err := resolveExtractors([]string{"squash"}, nil)
+	assert.NoError(t, err)
+
+	t.Run("Marks PRs reverted by a revert PR and by git revert", func(t *testing.T) {
+		commits := []Commit{
+			{Oid: "c4", Message: "Revert \"Fix: Bug (#102)\" (#105)\n\nReverts my-org/my-repo#102"},
+			{Oid: "c3", Message: "Revert \"Feat: New API (#101)\"\n\nThis reverts commit c1."},
+			{Oid: "c2", Message: "Fix: Bug (#102)"},
+			{Oid: "c1", Message: "Feat: New API (#101)"},
+		}
+
+		prs, _ := extractPRsFromCommits(commits, extractors)
+		assert.Len(t, prs, 3)
+		assert.Equal(t, 105, prs[0].Number)
+		assert.Empty(t, prs[0].RevertedBy)
+		assert.Equal(t, 102, prs[1].Number)
+		assert.Equal(t, "c4", prs[1].RevertedBy)
+		assert.Equal(t, 105, prs[1].RevertedByPR)
+		assert.Equal(t, 101, prs[2].Number)
+		assert.Equal(t, "c3", prs[2].RevertedBy)
+		assert.Zero(t, prs[2].RevertedByPR)
+	})
+
+	t.Run("Re-applied PR stays pending", func(t *testing.T) {
+		commits := []Commit{
+			{Oid: "c3", Message: "Revert \"Revert \"Feat: New API (#101)\"\"\n\nThis reverts commit c2."},
+			{Oid: "c2", Message: "Revert \"Feat: New API (#101)\"\n\nThis reverts commit c1."},
+			{Oid: "c1", Message: "Feat: New API (#101)"},
+		}
+
+		prs, _ := extractPRsFromCommits(commits, extractors)
+		assert.Len(t, prs, 1)
+		assert.Equal(t, 101, prs[0].Number)
+		assert.Equal(t, "c1", prs[0].Oid)
+		assert.Empty(t, prs[0].RevertedBy)
+	})
+}
@@ -0,0 +1,181 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/astein-peddi/git-tooling/completion"
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/theme"
+	"github.com/astein-peddi/git-tooling/utils"
+	"github.com/cli/shurcooL-graphql"
+)
+
+const (
+	snapshotSourceGitHub = "github"
+	snapshotSourceLocal  = "local"
+)
+
+// branchSnapshot is the commit a branch was scanned at. In API mode that is
+// the head on GitHub when the scan started; LocalHead is the remote-tracking
+// ref it was checked against, which the local-only steps such as backport
+// detection read.
+type branchSnapshot struct {
+	Branch    string `json:"branch"`
+	Head      string `json:"head"`
+	Source    string `json:"source"`
+	LocalHead string `json:"localHead,omitempty"`
+}
+
+// stale reports whether the local remote-tracking ref points somewhere else
+// than GitHub, typically because it was not fetched recently.
+func (s branchSnapshot) stale() bool {
+	return s.LocalHead != "" && s.LocalHead != s.Head
+}
+
+func (s branchSnapshot) String() string {
+	return fmt.Sprintf("%s@%s", s.Branch, shortOid(s.Head))
+}
+
+// resolveSnapshots pins every branch to a commit before it is scanned. In API
+// mode the live head is read from GitHub and compared with the local
+// remote-tracking ref. Stale refs are fetched when --fetch is set, and
+// reported otherwise.
+func (c scanConfig) resolveSnapshots(ctx context.Context, client models.GQLClient, branches []string) ([]branchSnapshot, error) {
+	snapshots := make([]branchSnapshot, len(branches))
+	for i, branch := range branches {
+		if c.isLocal {
+			head, err := utils.ResolveLocalRevision(branch)
+			if err != nil {
+				return nil, err
+			}
+			snapshots[i] = branchSnapshot{Branch: branch, Head: head, Source: snapshotSourceLocal}
+			continue
+		}
+
+		head, err := fetchRemoteHead(ctx, client, c.owner, c.repo, utils.RemoteRevision(branch))
+		if err != nil {
+			return nil, err
+		}
+		snapshots[i] = branchSnapshot{Branch: branch, Head: head, Source: snapshotSourceGitHub, LocalHead: localTrackingHead(branch)}
+	}
+
+	if c.fetch && anyStale(snapshots) {
+		completion.FetchAllBranches(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		for i := range snapshots {
+			snapshots[i].LocalHead = localTrackingHead(snapshots[i].Branch)
+		}
+	}
+
+	for _, snapshot := range snapshots {
+		if snapshot.stale() {
+			fmt.Fprintf(os.Stderr, "Warning: local '%s' is at %s but GitHub is at %s. Results reflect GitHub; local-only checks may be off until you run `git fetch` or pass --fetch.\n",
+				utils.RemoteTrackingRevision(snapshot.Branch), shortOid(snapshot.LocalHead), shortOid(snapshot.Head))
+		}
+	}
+
+	return snapshots, nil
+}
+
+func localTrackingHead(branch string) string {
+	ref := utils.RemoteTrackingRevision(branch)
+	if ref == branch {
+		// A local branch without a remote-tracking ref says nothing about
+		// what has been fetched from GitHub.
+		if _, err := utils.ResolveLocalRevision("refs/heads/" + branch); err == nil {
+			return ""
+		}
+	}
+
+	head, err := utils.ResolveLocalRevision(ref)
+	if err != nil {
+		return ""
+	}
+
+	return head
+}
+
+func anyStale(snapshots []branchSnapshot) bool {
+	for _, snapshot := range snapshots {
+		if snapshot.stale() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func fetchRemoteHead(ctx context.Context, client models.GQLClient, owner, repo, revision string) (string, error) {
+	var query struct {
+		Repository struct {
+			Object *struct {
+				Commit struct {
+					Oid string
+				} `graphql:"... on Commit"`
+			} `graphql:"object(expression: $revision)"`
+		} `graphql:"repository(owner: $owner, name: $repo)"`
+	}
+
+	variables := map[string]any{
+		"owner":    graphql.String(owner),
+		"repo":     graphql.String(repo),
+		"revision": graphql.String(revision),
+	}
+
+	if err := client.Query(ctx, "RevisionHead", &query, variables); err != nil {
+		return "", fmt.Errorf("failed to read the head of '%s' on GitHub: %w", revision, err)
+	}
+	if query.Repository.Object == nil || query.Repository.Object.Commit.Oid == "" {
+		return "", fmt.Errorf("revision '%s' does not exist on GitHub", revision)
+	}
+
+	return query.Repository.Object.Commit.Oid, nil
+}
+
+// snapshotSummary names the commits the results were computed from, e.g.
+// "dev@1a2b3c4, main@5d6e7f8 (GitHub)".
+func snapshotSummary(snapshots []branchSnapshot) string {
+	if len(snapshots) == 0 {
+		return ""
+	}
+
+	var heads []string
+	for _, snapshot := range snapshots {
+		heads = append(heads, snapshot.String())
+	}
+
+	source := "local refs"
+	if snapshots[0].Source == snapshotSourceGitHub {
+		source = "GitHub"
+	}
+
+	return fmt.Sprintf("%s (%s)", strings.Join(heads, ", "), source)
+}
+
+// snapshotHeader is the snapshot line shown under a TUI title, highlighted
+// when a local ref disagrees with GitHub.
+func snapshotHeader(snapshots []branchSnapshot) string {
+	summary := snapshotSummary(snapshots)
+	if summary == "" {
+		return ""
+	}
+
+	line := theme.DefaultTheme.MutedText.Render("Snapshot: " + summary)
+	if anyStale(snapshots) {
+		line += "\n" + theme.DefaultTheme.Warning.Render("Local remote-tracking refs are behind GitHub; run `git fetch` or pass --fetch.")
+	}
+
+	return line + "\n\n"
+}
+
+// printSnapshot reports the snapshot on stderr, keeping piped output clean.
+func printSnapshot(snapshots []branchSnapshot) {
+	if summary := snapshotSummary(snapshots); summary != "" {
+		fmt.Fprintf(os.Stderr, "Snapshot: %s\n", summary)
+	}
+}
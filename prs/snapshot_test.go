@@ -0,0 +1,60 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchRemoteHead(t *testing.T) {
+	t.Run("returns the commit the revision points to on GitHub", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{`{"repository": {"object": {"commit": {"oid": "abc1234def"}}}}`}}
+
+		head, err := fetchRemoteHead(context.Background(), client, "owner", "repo", "origin/main")
+		assert.NoError(t, err)
+		assert.Equal(t, "abc1234def", head)
+	})
+
+	t.Run("fails when the revision does not exist", func(t *testing.T) {
+		client := &mockGQLClient{pages: []string{`{"repository": {"object": null}}`}}
+
+		_, err := fetchRemoteHead(context.Background(), client, "owner", "repo", "origin/missing")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "does not exist on GitHub")
+	})
+
+	t.Run("wraps API errors", func(t *testing.T) {
+		client := &mockGQLClient{mockErr: fmt.Errorf("API rate limit exceeded")}
+
+		_, err := fetchRemoteHead(context.Background(), client, "owner", "repo", "origin/main")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "API rate limit exceeded")
+	})
+}
+
+func TestBranchSnapshot(t *testing.T) {
+	t.Run("is stale when the local ref points elsewhere", func(t *testing.T) {
+		assert.True(t, branchSnapshot{Head: "aaa", LocalHead: "bbb"}.stale())
+		assert.False(t, branchSnapshot{Head: "aaa", LocalHead: "aaa"}.stale())
+		assert.False(t, branchSnapshot{Head: "aaa"}.stale())
+	})
+
+	t.Run("summarises the heads and where they came from", func(t *testing.T) {
+		snapshots := []branchSnapshot{
+			{Branch: "dev", Head: "1a2b3c4d5e", Source: snapshotSourceGitHub},
+			{Branch: "main", Head: "5d6e7f8a9b", Source: snapshotSourceGitHub},
+		}
+		assert.Equal(t, "dev@1a2b3c4, main@5d6e7f8 (GitHub)", snapshotSummary(snapshots))
+
+		snapshots[0].Source = snapshotSourceLocal
+		assert.Equal(t, "dev@1a2b3c4, main@5d6e7f8 (local refs)", snapshotSummary(snapshots))
+		assert.Equal(t, "", snapshotSummary(nil))
+	})
+
+	t.Run("flags any stale ref", func(t *testing.T) {
+		assert.True(t, anyStale([]branchSnapshot{{Head: "a"}, {Head: "b", LocalHead: "c"}}))
+		assert.False(t, anyStale([]branchSnapshot{{Head: "a", LocalHead: "a"}}))
+	})
+}
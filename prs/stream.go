@@ -0,0 +1,66 @@
+package prs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/charmbracelet/bubbletea"
+)
+
+// streamComparison opens the table right away and fills it while both
+// branches are scanned newest first. Each page updates the preview of the
+// comparison; the final result, with PR details and backports, replaces it
+// once the scan ends. Stopping the scan from the table cancels the remaining
+// requests and keeps the last preview. Quitting waits for the scan to return,
+// so no request outlives the command.
+func streamComparison(ctx context.Context, config scanConfig, branchA, branchB string, symmetric, showCommits bool) error {
+	scanCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	p := tea.NewProgram(initialModel(branchA, branchB, symmetric, stop), tea.WithAltScreen(), tea.WithContext(ctx))
+
+	progress := make(chan loader.Progress, 64)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for update := range progress {
+			p.Send(update)
+		}
+	}()
+
+	visible := func(result comparison) comparison {
+		if !showCommits {
+			result.commits = nil
+		}
+		return result
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		result, err := config.compareBranches(scanCtx, progress, branchA, branchB, symmetric, func(partial comparison) {
+			p.Send(partialMsg(visible(partial)))
+		})
+		close(progress)
+		<-forwarded
+		p.Send(scanDoneMsg{result: visible(result), err: err})
+	}()
+
+	finalModel, err := p.Run()
+	stop()
+	<-done
+
+	if ctx.Err() != nil {
+		return loader.ErrCancelled
+	}
+	if err != nil {
+		return fmt.Errorf("error running table: %w", err)
+	}
+
+	if final, ok := finalModel.(model); ok && final.err != nil {
+		return final.err
+	}
+
+	return nil
+}
@@ -2,44 +2,130 @@ package prs
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/astein-peddi/git-tooling/models"
+	"github.com/astein-peddi/git-tooling/loader"
 	"github.com/astein-peddi/git-tooling/theme"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/table"
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/pkg/browser"
 )
 
+// partialMsg is a preview of the comparison while the branches are still
+// being scanned.
+type partialMsg comparison
+
+// scanDoneMsg ends the scan with the full comparison, or with the error that
+// stopped it.
+type scanDoneMsg struct {
+	result comparison
+	err    error
+}
+
 type model struct {
-	branchA string
-	branchB string
-	prs     []models.PR
-	table   table.Model
+	branchA   string
+	branchB   string
+	prs       []ComparedPR
+	commits   []ComparedCommit
+	symmetric bool
+	snapshots []branchSnapshot
+	table     table.Model
+	rowURLs   []string
+	width     int
+	spinner   spinner.Model
+	progress  map[string]loader.Progress
+	scanning  bool
+	stopped   bool
+	stop      func()
+	err       error
+
+	// unfiltered is set while the rows come from a preview that skipped
+	// the author and label filters.
+	unfiltered bool
 }
 
-func initialModel(branchA, branchB string, prs []models.PR) model {
+// initialModel opens the table before anything is scanned. Previews, progress
+// and the final comparison arrive as messages; stop cancels the scan and
+// keeps the last preview.
+func initialModel(branchA, branchB string, symmetric bool, stop func()) model {
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = theme.DefaultTheme.Spinner
+
 	return model{
-		branchA: branchA,
-		branchB: branchB,
-		prs:     prs,
+		branchA:   branchA,
+		branchB:   branchB,
+		symmetric: symmetric,
+		spinner:   s,
+		progress:  make(map[string]loader.Progress),
+		scanning:  true,
+		stop:      stop,
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return tea.WindowSize()
+	return tea.Batch(tea.WindowSize(), m.spinner.Tick)
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	switch msg := msg.(type) {
 		case tea.WindowSizeMsg:
-			m.table = setupTable(msg.Width, m.prs)
+			m.width = msg.Width
+			m.refreshTable()
+			return m, nil
+
+		case spinner.TickMsg:
+			if !m.scanning {
+				return m, nil
+			}
+			m.spinner, cmd = m.spinner.Update(msg)
+			return m, cmd
+
+		case loader.Progress:
+			m.progress[msg.Label] = msg
+			return m, nil
+
+		case partialMsg:
+			if !m.scanning {
+				return m, nil
+			}
+			m.setResult(comparison(msg))
+			return m, nil
+
+		case scanDoneMsg:
+			m.scanning = false
+			if m.stopped {
+				return m, nil
+			}
+			if msg.err != nil {
+				m.err = msg.err
+				return m, tea.Quit
+			}
+			m.setResult(msg.result)
 			return m, nil
 
 		case tea.KeyMsg:
 			switch msg.String() {
 				case "q", "ctrl+c":
 					return m, tea.Quit
+
+				case "s":
+					if !m.scanning || m.stopped {
+						return m, nil
+					}
+					m.stopped = true
+					return m, stopScanCmd(m.stop)
+
+				case "enter":
+					url := m.selectedURL()
+					if url == "" {
+						return m, nil
+					}
+
+					return m, openURLCmd(url)
 			}
 	}
 	
@@ -47,7 +133,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-// In prs/ui.go
+func (m *model) setResult(result comparison) {
+	m.prs = result.prs
+	m.commits = result.commits
+	m.snapshots = result.snapshots
+	m.unfiltered = result.unfiltered
+	m.refreshTable()
+}
+
+// refreshTable rebuilds the table for the current results, keeping the
+// cursor where it was.
+func (m *model) refreshTable() {
+	if m.width == 0 {
+		return
+	}
+
+	cursor := m.table.Cursor()
+	m.table, m.rowURLs = setupTable(m.width, m.branchA, m.branchB, m.prs, m.commits, m.symmetric)
+	if cursor > 0 && len(m.rowURLs) > 0 {
+		m.table.SetCursor(min(cursor, len(m.rowURLs)-1))
+	}
+}
+
+func (m model) selectedURL() string {
+	cursor := m.table.Cursor()
+	if cursor < 0 || cursor >= len(m.rowURLs) {
+		return ""
+	}
+
+	return m.rowURLs[cursor]
+}
 
 func (m model) View() string {
 	if m.table.Columns() == nil {
@@ -56,20 +171,34 @@ func (m model) View() string {
 
 	var header, footer string
 	
-	header = fmt.Sprintf("PRs merged into '%s' but not in '%s'\n\n", m.branchA, m.branchB)
+	header = fmt.Sprintf("PRs merged into '%s' but not in '%s'\n", m.branchA, m.branchB)
+	if m.symmetric {
+		header = fmt.Sprintf("PRs that differ between '%s' and '%s'\n", m.branchA, m.branchB)
+	}
+	header += snapshotHeader(m.snapshots)
+	header += m.scanStatus()
 	
+	hasRows := len(m.prs) > 0 || len(m.commits) > 0
+
 	helpText := "(q to quit)"
-	if len(m.prs) > 0 {
-		helpText = "(↑/↓ to move or Vim Motions, q to quit)"
-		paginationText := fmt.Sprintf("%d/%d", m.table.Cursor()+1, len(m.prs))
+	if hasRows {
+		helpText = "(↑/↓ to move or Vim Motions, Enter to open, q to quit)"
+	}
+	if m.scanning && !m.stopped {
+		helpText = strings.TrimSuffix(helpText, ")") + ", s to stop scanning)"
+	}
+	if hasRows {
+		paginationText := fmt.Sprintf("%d/%d", m.table.Cursor()+1, len(m.rowURLs))
 		footer = fmt.Sprintf("\n\n%s  %s", helpText, paginationText)
 	} else {
 		footer = fmt.Sprintf("\n\n%s", helpText)
 	}
 	
 	var body string
-	if len(m.prs) > 0 {
+	if hasRows {
 		body = m.table.View()
+	} else if m.scanning {
+		body = "No differences found yet."
 	} else {
 		body = "No differences found. (q to quit)"
 	}
@@ -81,14 +210,64 @@ func (m model) View() string {
 	)
 }
 
-func setupTable(termWidth int, prs []models.PR) table.Model {
-	numWidth := 10
-	padding := 8
-	titleWidth := max(termWidth - numWidth - padding, 20)
+// scanStatus is the line above the table that says whether the results are
+// still growing, with the progress of each branch, or were cut short.
+func (m model) scanStatus() string {
+	if m.stopped {
+		warning := fmt.Sprintf("Scan stopped early: some of these PRs may already be in '%s', and PR details may be missing.", m.branchB)
+		if m.unfiltered {
+			warning += " The author and label filters were not applied."
+		}
+		return theme.DefaultTheme.Warning.Render(warning) + "\n"
+	}
+	if !m.scanning {
+		return ""
+	}
+
+	var parts []string
+	for _, branch := range []string{m.branchA, m.branchB} {
+		if progress, ok := m.progress[branch]; ok && progress.String() != "" {
+			parts = append(parts, fmt.Sprintf("%s: %s", branch, progress))
+		}
+	}
+	status := "Still scanning"
+	if len(parts) > 0 {
+		status += " · " + strings.Join(parts, ", ")
+	}
+	if m.unfiltered {
+		status += " · not filtered by author or label until PR details are loaded"
+	}
+
+	return fmt.Sprintf("%s %s\n", m.spinner.View(), theme.DefaultTheme.MutedText.Render(status))
+}
+
+func stopScanCmd(stop func()) tea.Cmd {
+	return func() tea.Msg {
+		stop()
+		return nil
+	}
+}
+
+func setupTable(termWidth int, branchA, branchB string, prs []ComparedPR, commits []ComparedCommit, symmetric bool) (table.Model, []string) {
+	numWidth := 8
+	authorWidth := 16
+	mergedWidth := 12
+	labelsWidth := 18
+	baseWidth := 12
+	closesWidth := 10
+	statusWidth := 16
+	padding := 18
+	titleWidth := max(termWidth - numWidth - authorWidth - mergedWidth - labelsWidth - baseWidth - closesWidth - statusWidth - padding, 20)
 
 	columns := []table.Column{
 		{Title: "Number", Width: numWidth},
 		{Title: "Title", Width: titleWidth},
+		{Title: "Author", Width: authorWidth},
+		{Title: "Merged", Width: mergedWidth},
+		{Title: "Labels", Width: labelsWidth},
+		{Title: "Base", Width: baseWidth},
+		{Title: "Closes", Width: closesWidth},
+		{Title: "Status", Width: statusWidth},
 	}
 
 	tbl := table.New(
@@ -103,9 +282,93 @@ func setupTable(termWidth int, prs []models.PR) table.Model {
 	tbl.SetStyles(styles)
 
 	rows := []table.Row{}
-	for _, pr := range prs {
-		rows = append(rows, table.Row{fmt.Sprintf("#%d", pr.Number), pr.Title})
+	var rowURLs []string
+	addDivider := func(text string) {
+		rows = append(rows, table.Row{"", theme.DefaultTheme.Divider.Render(text)})
+		rowURLs = append(rowURLs, "")
+	}
+	addSection := func(direction string) {
+		for _, pr := range prs {
+			if pr.Direction != direction {
+				continue
+			}
+			rows = append(rows, table.Row{
+				fmt.Sprintf("#%d", pr.Number),
+				pr.Title,
+				pr.Author,
+				shortDate(pr.MergedAt),
+				strings.Join(pr.Labels, ", "),
+				pr.BaseRef,
+				issueList(pr.ClosingIssues),
+				statusText(pr),
+			})
+			rowURLs = append(rowURLs, pr.URL)
+		}
+
+		dividerAdded := false
+		for _, commit := range commits {
+			if commit.Direction != direction {
+				continue
+			}
+			if !dividerAdded {
+				addDivider("-- commits without a PR --")
+				dividerAdded = true
+			}
+			rows = append(rows, table.Row{
+				shortOid(commit.Oid),
+				commit.Subject,
+				commit.Author,
+				shortDate(commit.Date),
+				"",
+				"",
+				"",
+				commitStatusText(commit),
+			})
+			rowURLs = append(rowURLs, commit.URL)
+		}
+	}
+
+	if !symmetric {
+		addSection(directionForward)
+	} else {
+		for _, section := range []struct{ direction, from, to string }{
+			{directionForward, branchA, branchB},
+			{directionReverse, branchB, branchA},
+		} {
+			addDivider(fmt.Sprintf("-- in %s, not in %s --", section.from, section.to))
+			addSection(section.direction)
+		}
 	}
+
 	tbl.SetRows(rows)
-	return tbl
-}
\ No newline at end of file
+	return tbl, rowURLs
+}
+
+func statusText(pr ComparedPR) string {
+	if pr.Status == statusBackported {
+		return fmt.Sprintf("via backport #%d", pr.BackportedAs)
+	}
+	if pr.Status == statusReverted {
+		return "reverted by " + revertReference(pr.RevertedBy, pr.RevertedByPR)
+	}
+
+	return pr.Status
+}
+
+func commitStatusText(commit ComparedCommit) string {
+	if commit.Status == statusBackported {
+		return "via backport " + shortOid(commit.BackportedAs)
+	}
+	if commit.Status == statusReverted {
+		return "reverted by " + revertReference(commit.RevertedBy, commit.RevertedByPR)
+	}
+
+	return commit.Status
+}
+
+func openURLCmd(url string) tea.Cmd {
+	return func() tea.Msg {
+		go browser.OpenURL(url)
+		return nil
+	}
+}
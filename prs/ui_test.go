@@ -0,0 +1,79 @@
+package prs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/astein-peddi/git-tooling/loader"
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func streamedModel(t *testing.T, stop func()) model {
+	t.Helper()
+
+	updated, _ := initialModel("dev", "main", false, stop).Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	return updated.(model)
+}
+
+func TestModelStreamsResults(t *testing.T) {
+	preview := comparison{prs: []ComparedPR{{PR: models.PR{Number: 102, Title: "Fix (#102)"}, Status: statusPending, Direction: directionForward}}}
+	final := comparison{prs: []ComparedPR{
+		{PR: models.PR{Number: 102, Title: "Fix", Author: "jane"}, Status: statusPending, Direction: directionForward},
+		{PR: models.PR{Number: 101, Title: "Feat", Author: "john"}, Status: statusPending, Direction: directionForward},
+	}}
+
+	t.Run("Shows previews until the scan ends", func(t *testing.T) {
+		m := streamedModel(t, func() {})
+
+		updated, _ := m.Update(loader.Progress{Label: "dev", Pages: 1, Commits: 100})
+		updated, _ = updated.Update(partialMsg(preview))
+		view := updated.View()
+		assert.Contains(t, view, "Still scanning · dev: 1 page · 100 commits")
+		assert.Contains(t, view, "s to stop scanning")
+		assert.Contains(t, view, "Fix (#102)")
+
+		updated, _ = updated.Update(scanDoneMsg{result: final})
+		view = updated.View()
+		assert.NotContains(t, view, "Still scanning")
+		assert.Len(t, updated.(model).rowURLs, 2)
+	})
+
+	t.Run("Stopping keeps the last preview", func(t *testing.T) {
+		stopped := false
+		m := streamedModel(t, func() { stopped = true })
+
+		updated, _ := m.Update(partialMsg(preview))
+		updated, cmd := updated.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+		cmd()
+		assert.True(t, stopped)
+
+		updated, _ = updated.Update(scanDoneMsg{err: errors.New("context canceled")})
+		updated, _ = updated.Update(partialMsg(final))
+		assert.Len(t, updated.(model).rowURLs, 1)
+		assert.NoError(t, updated.(model).err)
+		assert.Contains(t, updated.View(), "Scan stopped early")
+	})
+
+	t.Run("Says when previews are not filtered yet", func(t *testing.T) {
+		m := streamedModel(t, func() {})
+
+		unfiltered := preview
+		unfiltered.unfiltered = true
+		updated, _ := m.Update(partialMsg(unfiltered))
+		assert.Contains(t, updated.View(), "not filtered by author or label")
+
+		updated, _ = updated.Update(scanDoneMsg{result: final})
+		assert.NotContains(t, updated.View(), "not filtered")
+	})
+
+	t.Run("Quits with the error that ended the scan", func(t *testing.T) {
+		m := streamedModel(t, func() {})
+
+		updated, cmd := m.Update(scanDoneMsg{err: errors.New("API rate limit exceeded")})
+		assert.EqualError(t, updated.(model).err, "API rate limit exceeded")
+		assert.IsType(t, tea.QuitMsg{}, cmd())
+	})
+}
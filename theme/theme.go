@@ -8,6 +8,7 @@ type AppTheme struct {
 	TableHeader      lipgloss.Style
 	Divider          lipgloss.Style
 	MutedText        lipgloss.Style
+	Warning          lipgloss.Style
 }
 
 var DefaultTheme = NordTheme
@@ -18,6 +19,7 @@ var OriginalTheme = AppTheme{
 	TableHeader:      lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("240")).BorderBottom(true),
 	Divider:          lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("240")),
 	MutedText:        lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+	Warning:          lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
 }
 
 // MonokaiTheme is inspired by the popular Monokai editor theme.
@@ -27,6 +29,7 @@ var MonokaiTheme = AppTheme{
 	TableHeader:      lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("244")).BorderBottom(true),
 	Divider:          lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#A6E22E")), // Green
 	MutedText:        lipgloss.NewStyle().Foreground(lipgloss.Color("244")),
+	Warning:          lipgloss.NewStyle().Foreground(lipgloss.Color("#FD971F")), // Orange
 }
 
 // GruvboxTheme uses a retro, warm color palette.
@@ -36,6 +39,7 @@ var GruvboxTheme = AppTheme{
 	TableHeader:      lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("245")).BorderBottom(true),
 	Divider:          lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#458588")), // Blue/Aqua
 	MutedText:        lipgloss.NewStyle().Foreground(lipgloss.Color("245")),
+	Warning:          lipgloss.NewStyle().Foreground(lipgloss.Color("#FABD2F")), // Yellow
 }
 
 // NordTheme is a cool, elegant, arctic-inspired theme.
@@ -45,6 +49,7 @@ var NordTheme = AppTheme{
 	TableHeader:      lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderForeground(lipgloss.Color("#4C566A")).BorderBottom(true),
 	Divider:          lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#88C0D0")), // Light Blue
 	MutedText:        lipgloss.NewStyle().Foreground(lipgloss.Color("#4C566A")),
+	Warning:          lipgloss.NewStyle().Foreground(lipgloss.Color("#EBCB8B")), // Yellow
 }
 
 // MonochromeTheme is a simple, high-contrast theme that works on all terminals.
@@ -54,4 +59,5 @@ var MonochromeTheme = AppTheme{
 	TableHeader:      lipgloss.NewStyle().BorderStyle(lipgloss.NormalBorder()).BorderBottom(true).Bold(true),
 	Divider:          lipgloss.NewStyle().Faint(true), // Dim text
 	MutedText:        lipgloss.NewStyle().Faint(true),
+	Warning:          lipgloss.NewStyle().Bold(true).Underline(true),
 }
\ No newline at end of file
@@ -0,0 +1,59 @@
+package utils
+
+// Exclusion tells apart the commits of a history that are reachable from its
+// head but not from any stop commit, as `git log head ^stop` does. The
+// history is visited newest first, with every commit before its parents, the
+// order both git log and GitHub's history connection use. A history with
+// merges can list a stop commit before older commits of a merged branch, so
+// the scan can only end once every commit still to come is known to be an
+// ancestor of a stop commit.
+type Exclusion struct {
+	excluded map[string]bool
+	wanted   map[string]bool
+	active   bool
+	visited  bool
+}
+
+func NewExclusion(stopAt []string) *Exclusion {
+	e := &Exclusion{
+		excluded: make(map[string]bool),
+		wanted:   make(map[string]bool),
+		active:   len(stopAt) > 0,
+	}
+	for _, oid := range stopAt {
+		e.excluded[oid] = true
+	}
+
+	return e
+}
+
+// Keep visits the next commit of the history and reports whether it belongs
+// to the result.
+func (e *Exclusion) Keep(oid string, parents []string) bool {
+	e.visited = true
+	delete(e.wanted, oid)
+
+	if e.excluded[oid] {
+		for _, parent := range parents {
+			e.excluded[parent] = true
+			delete(e.wanted, parent)
+		}
+
+		return false
+	}
+
+	for _, parent := range parents {
+		if !e.excluded[parent] {
+			e.wanted[parent] = true
+		}
+	}
+
+	return true
+}
+
+// Done reports whether the rest of the history only holds ancestors of the
+// stop commits. Without stop commits the whole history belongs to the
+// result, so Done never reports true.
+func (e *Exclusion) Done() bool {
+	return e.active && e.visited && len(e.wanted) == 0
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExclusion(t *testing.T) {
+	type commit struct {
+		oid     string
+		parents []string
+	}
+
+	// dev merged main (c3) after committing c2 and c1 on top of c0.
+	history := []commit{
+		{"m1", []string{"c2", "c3"}},
+		{"c3", []string{"c0"}},
+		{"c2", []string{"c1"}},
+		{"c1", []string{"c0"}},
+		{"c0", nil},
+	}
+
+	t.Run("Keeps what the stop commits cannot reach", func(t *testing.T) {
+		exclusion := NewExclusion([]string{"c3"})
+
+		var kept []string
+		for _, c := range history {
+			if exclusion.Keep(c.oid, c.parents) {
+				kept = append(kept, c.oid)
+			}
+			if exclusion.Done() {
+				break
+			}
+		}
+		assert.Equal(t, []string{"m1", "c2", "c1"}, kept)
+	})
+
+	t.Run("Never ends without stop commits", func(t *testing.T) {
+		exclusion := NewExclusion(nil)
+		for _, c := range history {
+			assert.True(t, exclusion.Keep(c.oid, c.parents))
+			assert.False(t, exclusion.Done())
+		}
+	})
+}
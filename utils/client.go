@@ -1,23 +1,39 @@
 package utils
 
 import (
+	"context"
 	"fmt"
+	"net/http"
+	"time"
 
 	"github.com/astein-peddi/git-tooling/models"
 	"github.com/cli/go-gh/v2/pkg/api"
 	"github.com/cli/shurcooL-graphql"
 )
 
+// GetGhGraphQLClient returns a GraphQL client authenticated through the GitHub
+// CLI, which retries transient failures and waits out rate limits.
 func GetGhGraphQLClient() (models.GQLClient, error) {
-	client, err := api.DefaultGraphQLClient()
+	transport := &rateLimitTransport{base: http.DefaultTransport, now: time.Now}
+	client, err := api.NewGraphQLClient(api.ClientOptions{Transport: transport})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GraphQL client: %w. Please verify GitHub CLI is installed and run `gh auth login`", err)
 	}
 
-	return client, nil
+	return newRateLimitClient(ghGraphQLClient{client}), nil
 }
 
-func GetGhUsernameGraphQL() (string, error) {
+// ghGraphQLClient adapts the go-gh client to models.GQLClient, so every query
+// is bound to the context of the command that runs it.
+type ghGraphQLClient struct {
+	client *api.GraphQLClient
+}
+
+func (c ghGraphQLClient) Query(ctx context.Context, name string, response any, variables map[string]any) error {
+	return c.client.QueryWithContext(ctx, name, response, variables)
+}
+
+func GetGhUsernameGraphQL(ctx context.Context) (string, error) {
 	client, err := GetGhGraphQLClient()
 	if err != nil {
 		return "", err
@@ -29,7 +45,7 @@ func GetGhUsernameGraphQL() (string, error) {
 		}
 	}
 
-	err = client.Query("ViewerLogin", &query, nil)
+	err = client.Query(ctx, "ViewerLogin", &query, nil)
 	if err != nil {
 		return "", err
 	}
@@ -37,7 +53,7 @@ func GetGhUsernameGraphQL() (string, error) {
 	return query.Viewer.Login, nil
 }
 
-func DoesGhBranchExistGraphQL(branch string) (bool, error) {
+func DoesGhRevisionExistGraphQL(ctx context.Context, expression string) (bool, error) {
 	client, err := GetGhGraphQLClient()
 	if err != nil {
 		return false, err
@@ -50,22 +66,48 @@ func DoesGhBranchExistGraphQL(branch string) (bool, error) {
 
 	var query struct {
 		Repository struct {
-			Ref *struct {
-				ID string
-			}
+			Object *struct {
+				Commit struct {
+					Oid string
+				} `graphql:"... on Commit"`
+			} `graphql:"object(expression: $expression)"`
 		} `graphql:"repository(owner: $owner, name: $repo)"`
 	}
 
 	variables := map[string]any{
-		"owner":  graphql.String(owner),
-		"repo":   graphql.String(repo),
-		"branch": graphql.String("refs/heads/" + branch),
+		"owner":      graphql.String(owner),
+		"repo":       graphql.String(repo),
+		"expression": graphql.String(expression),
 	}
 
-	err = client.Query("BranchExists", &query, variables)
+	err = client.Query(ctx, "RevisionExists", &query, variables)
 	if err != nil {
 		return false, err
 	}
 
-	return query.Repository.Ref != nil, nil
+	return query.Repository.Object != nil && query.Repository.Object.Commit.Oid != "", nil
+}
+
+func GetMergeBaseFromAPI(ctx context.Context, owner, repo, base, head string) (string, error) {
+	client, err := api.DefaultRESTClient()
+	if err != nil {
+		return "", fmt.Errorf("failed to create REST client: %w. Please verify GitHub CLI is installed and run `gh auth login`", err)
+	}
+
+	var response struct {
+		MergeBaseCommit struct {
+			Sha string `json:"sha"`
+		} `json:"merge_base_commit"`
+	}
+
+	path := fmt.Sprintf("repos/%s/%s/compare/%s...%s?per_page=1", owner, repo, base, head)
+	if err := client.DoWithContext(ctx, http.MethodGet, path, nil, &response); err != nil {
+		return "", fmt.Errorf("failed to compare '%s' and '%s': %w", base, head, err)
+	}
+
+	if response.MergeBaseCommit.Sha == "" {
+		return "", fmt.Errorf("no merge base found between '%s' and '%s'", base, head)
+	}
+
+	return response.MergeBaseCommit.Sha, nil
 }
@@ -0,0 +1,20 @@
+package utils
+
+// ExitError asks main to exit with a specific status code instead of the
+// generic 1. Err, when set, is printed to stderr before exiting.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return ""
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"net/url"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -16,13 +19,15 @@ func IsInsideGitRepository() bool {
 	return true
 }
 
-func DoesBranchExist(branch string, localOnly bool) bool {
+// DoesRevisionExist reports whether a revision (branch, tag, SHA, remote
+// tracking ref or an expression such as HEAD~50) names a commit locally, or
+// on GitHub when localOnly is false.
+func DoesRevisionExist(ctx context.Context, revision string, localOnly bool) bool {
 	if !IsInsideGitRepository() {
 		return false
 	}
 
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/"+branch)
-	if err := cmd.Run(); err == nil {
+	if _, err := ResolveLocalRevision(revision); err == nil {
 		return true
 	}
 
@@ -30,7 +35,7 @@ func DoesBranchExist(branch string, localOnly bool) bool {
 		return false
 	}
 
-	exists, err := DoesGhBranchExistGraphQL(branch)
+	exists, err := DoesGhRevisionExistGraphQL(ctx, RemoteRevision(revision))
 	if err != nil {
 		return false
 	}
@@ -38,6 +43,59 @@ func DoesBranchExist(branch string, localOnly bool) bool {
 	return exists
 }
 
+func ResolveLocalRevision(revision string) (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--verify", "--quiet", revision+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("could not resolve revision '%s': %w", revision, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// GitRemote is the remote that points at the GitHub repository: the
+// peddi-tooling.remote git config value when it is set, origin otherwise.
+func GitRemote() string {
+	out, err := exec.Command("git", "config", "--get", "peddi-tooling.remote").Output()
+	if err == nil {
+		if remote := strings.TrimSpace(string(out)); remote != "" {
+			return remote
+		}
+	}
+
+	return "origin"
+}
+
+// RemoteRevision translates a revision into an expression GitHub can
+// evaluate. Remote-tracking refs of the GitHub remote lose their remote
+// prefix, and relative expressions like HEAD~50 are resolved locally because
+// GitHub would apply them to its own HEAD rather than the local one.
+func RemoteRevision(revision string) string {
+	if strings.ContainsAny(revision, "~^@:") {
+		if oid, err := ResolveLocalRevision(revision); err == nil {
+			return oid
+		}
+	}
+
+	return strings.TrimPrefix(revision, GitRemote()+"/")
+}
+
+// RemoteTrackingRevision returns the local ref that mirrors what GitHub
+// knows about a revision: branches with a remote-tracking ref on the GitHub
+// remote map to it, while local-only branches, tags, SHAs and expressions
+// are used as they are.
+func RemoteTrackingRevision(revision string) string {
+	remote := GitRemote()
+	if strings.HasPrefix(revision, remote+"/") {
+		return revision
+	}
+
+	if err := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/remotes/"+remote+"/"+revision).Run(); err == nil {
+		return remote + "/" + revision
+	}
+
+	return revision
+}
+
 func GetBranchNames() []string {
 	if !IsInsideGitRepository() {
 		return []string{}
@@ -62,7 +120,7 @@ func GetBranchNames() []string {
 }
 
 func GetRepoOwnerAndName() (string, string, error) {
-	out, err := exec.Command("git", "remote", "get-url", "origin").Output()
+	out, err := exec.Command("git", "remote", "get-url", GitRemote()).Output()
 	if err != nil {
 		return "", "", fmt.Errorf("failed to get git remote: %w", err)
 	}
@@ -102,3 +160,87 @@ func parseGitRemoteURL(rawURL string) (string, string, error) {
 
 	return segments[0], segments[1], nil
 }
+
+func GetRepoDirName() string {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Base(strings.TrimSpace(string(out)))
+}
+
+func GetLocalMergeBase(refs ...string) (string, error) {
+	args := append([]string{"merge-base"}, refs...)
+	if len(refs) > 2 {
+		args = append([]string{"merge-base", "--octopus"}, refs...)
+	}
+
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("could not find a merge base for %s: %w", strings.Join(refs, ", "), err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+func GetPatchIDs(oids []string) (map[string]string, error) {
+	patchIDs := make(map[string]string)
+
+	available := filterExistingCommits(oids)
+	if len(available) == 0 {
+		return patchIDs, nil
+	}
+
+	logCmd := exec.Command("git", "log", "--no-walk=unsorted", "--stdin", "-p", "--diff-merges=first-parent", "--format=commit %H")
+	logCmd.Stdin = strings.NewReader(strings.Join(available, "\n") + "\n")
+	diff, err := logCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit diffs: %w", err)
+	}
+
+	patchCmd := exec.Command("git", "patch-id", "--stable")
+	patchCmd.Stdin = bytes.NewReader(diff)
+	out, err := patchCmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute patch ids: %w", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 {
+			patchIDs[fields[1]] = fields[0]
+		}
+	}
+
+	return patchIDs, nil
+}
+
+func filterExistingCommits(oids []string) []string {
+	var candidates []string
+	for _, oid := range oids {
+		if oid != "" {
+			candidates = append(candidates, oid)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	cmd := exec.Command("git", "cat-file", "--batch-check=%(objectname) %(objecttype)")
+	cmd.Stdin = strings.NewReader(strings.Join(candidates, "\n") + "\n")
+	out, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+
+	var existing []string
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "commit" {
+			existing = append(existing, fields[0])
+		}
+	}
+
+	return existing
+}
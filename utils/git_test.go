@@ -1,9 +1,12 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -141,4 +144,88 @@ func TestGetRepoOwnerAndName_Integration(t *testing.T) {
 	assert.NoError(t, err, "should not return an error when a remote 'origin' exists")
 	assert.NotEmpty(t, owner, "owner should not be empty")
 	assert.NotEmpty(t, repo, "repo should not be empty")
-}
\ No newline at end of file
+}
+
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func TestGetPatchIDs_Integration(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("skipping integration test: git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0644)
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "init")
+
+	runGit(t, dir, "checkout", "-q", "-b", "dev")
+	os.WriteFile(filepath.Join(dir, "b.txt"), []byte("b\n"), 0644)
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-q", "-m", "Hotfix (#11)")
+	original := runGit(t, dir, "rev-parse", "HEAD")
+
+	runGit(t, dir, "checkout", "-q", "main")
+	runGit(t, dir, "cherry-pick", original)
+	runGit(t, dir, "commit", "-q", "--amend", "-m", "Hotfix backport (#12)")
+	backport := runGit(t, dir, "rev-parse", "HEAD")
+	initial := runGit(t, dir, "rev-parse", "HEAD~1")
+
+	t.Chdir(dir)
+
+	patchIDs, err := GetPatchIDs([]string{original, backport, initial, "0000000000000000000000000000000000000000"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, patchIDs[original])
+	assert.Equal(t, patchIDs[original], patchIDs[backport])
+	assert.NotEqual(t, patchIDs[original], patchIDs[initial])
+	assert.NotContains(t, patchIDs, "0000000000000000000000000000000000000000")
+}
+
+func TestRevisionHelpers_Integration(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("skipping integration test: git is not installed")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "one")
+	first := runGit(t, dir, "rev-parse", "HEAD")
+	runGit(t, dir, "tag", "v1.0.0")
+	runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "two")
+	runGit(t, dir, "update-ref", "refs/remotes/origin/rtm", first)
+
+	t.Chdir(dir)
+
+	for _, revision := range []string{"main", "v1.0.0", first, first[:8], "origin/rtm", "HEAD~1"} {
+		assert.True(t, DoesRevisionExist(context.Background(), revision, true), revision)
+	}
+	assert.False(t, DoesRevisionExist(context.Background(), "missing", true))
+
+	assert.Equal(t, first, RemoteRevision("HEAD~1"))
+	assert.Equal(t, "rtm", RemoteRevision("origin/rtm"))
+	assert.Equal(t, "v1.0.0", RemoteRevision("v1.0.0"))
+
+	assert.Equal(t, "origin/rtm", RemoteTrackingRevision("rtm"))
+	assert.Equal(t, "main", RemoteTrackingRevision("main"), "local-only branches have no remote-tracking ref")
+	assert.Equal(t, "v1.0.0", RemoteTrackingRevision("v1.0.0"))
+
+	runGit(t, dir, "update-ref", "refs/remotes/origin/main", first)
+	assert.Equal(t, "origin/main", RemoteTrackingRevision("main"))
+
+	runGit(t, dir, "config", "peddi-tooling.remote", "upstream")
+	runGit(t, dir, "update-ref", "refs/remotes/upstream/rtm", first)
+	assert.Equal(t, "upstream", GitRemote())
+	assert.Equal(t, "rtm", RemoteRevision("upstream/rtm"))
+	assert.Equal(t, "upstream/rtm", RemoteTrackingRevision("rtm"))
+	assert.Equal(t, "main", RemoteTrackingRevision("main"))
+}
@@ -0,0 +1,309 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/astein-peddi/git-tooling/models"
+	"github.com/cli/go-gh/v2/pkg/api"
+)
+
+const (
+	maxQueryRetries  = 4
+	baseRetryDelay   = time.Second
+	maxRetryDelay    = 30 * time.Second
+	defaultLimitWait = time.Minute
+)
+
+// rateLimit is the GraphQL API budget GitHub reports after each query.
+type rateLimit struct {
+	Limit     int
+	Remaining int
+	Cost      int
+	ResetAt   time.Time
+}
+
+// rateLimitClient retries queries that fail for a transient reason and
+// spaces them out according to the GraphQL API budget. It asks for the
+// rateLimit object with every query, warns once when the budget runs low and
+// waits for the reset instead of failing when it runs out.
+type rateLimitClient struct {
+	client models.GQLClient
+	sleep  func(context.Context, time.Duration) error
+	now    func() time.Time
+	log    io.Writer
+
+	mu     sync.Mutex
+	budget rateLimit
+	known  bool
+	warned bool
+}
+
+func newRateLimitClient(client models.GQLClient) *rateLimitClient {
+	return &rateLimitClient{
+		client: client,
+		sleep:  sleep,
+		now:    time.Now,
+		log:    os.Stderr,
+	}
+}
+
+func (c *rateLimitClient) Query(ctx context.Context, name string, response any, variables map[string]any) error {
+	for attempt := 0; ; attempt++ {
+		if err := c.waitForBudget(ctx); err != nil {
+			return err
+		}
+
+		target, budget := withRateLimit(response)
+		err := c.client.Query(ctx, name, target, variables)
+		// GitHub can return data together with errors, for example for the
+		// aliased fields that resolved, so the decoded fields are passed on
+		// whether or not the query failed.
+		if budget != nil {
+			copyQueryFields(response, target)
+			c.record(budget())
+		}
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		delay, retryable := c.retryDelay(err, attempt)
+		if !retryable || attempt >= maxQueryRetries {
+			return err
+		}
+
+		fmt.Fprintf(c.log, "Warning: %s query failed (%v), retrying in %s.\n", name, err, delay.Round(time.Second))
+		if err := c.sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d, or returns early with the context error when ctx is
+// cancelled first.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// waitForBudget blocks until the budget resets when the last query left too
+// few points for another one of the same cost.
+func (c *rateLimitClient) waitForBudget(ctx context.Context) error {
+	c.mu.Lock()
+	budget, known := c.budget, c.known
+	c.mu.Unlock()
+
+	if !known || budget.Remaining >= max(budget.Cost, 1) {
+		return nil
+	}
+
+	wait := budget.ResetAt.Sub(c.now())
+	if wait <= 0 {
+		return nil
+	}
+
+	fmt.Fprintf(c.log, "Warning: GitHub API budget is used up, waiting %s until it resets at %s.\n", wait.Round(time.Second), budget.ResetAt.Local().Format(time.Kitchen))
+	if err := c.sleep(ctx, wait); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.known = false
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *rateLimitClient) record(budget rateLimit) {
+	if budget.ResetAt.IsZero() {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.budget = budget
+	c.known = true
+	if !c.warned && budget.Limit > 0 && budget.Remaining*10 < budget.Limit {
+		c.warned = true
+		fmt.Fprintf(c.log, "Warning: GitHub API budget is running low: %d of %d points left until %s.\n", budget.Remaining, budget.Limit, budget.ResetAt.Local().Format(time.Kitchen))
+	}
+}
+
+// retryDelay decides whether a failed query is worth another attempt, and
+// how long to wait before it. Rate limits wait for as long as GitHub asks;
+// server and network errors back off exponentially with jitter.
+func (c *rateLimitClient) retryDelay(err error, attempt int) (time.Duration, bool) {
+	var limited *rateLimitError
+	if errors.As(err, &limited) {
+		return limited.wait, true
+	}
+
+	var graphQLErr *api.GraphQLError
+	if errors.As(err, &graphQLErr) {
+		for _, item := range graphQLErr.Errors {
+			if item.Type == "RATE_LIMITED" {
+				return c.untilReset(), true
+			}
+		}
+		return 0, false
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return backoff(attempt), true
+	}
+
+	return 0, false
+}
+
+func (c *rateLimitClient) untilReset() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.known {
+		if wait := c.budget.ResetAt.Sub(c.now()); wait > 0 {
+			return wait
+		}
+	}
+
+	return defaultLimitWait
+}
+
+// backoff doubles the delay with every attempt and picks a random point in
+// its upper half, so parallel scans do not retry in lockstep.
+func backoff(attempt int) time.Duration {
+	delay := min(baseRetryDelay<<attempt, maxRetryDelay)
+
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// withRateLimit extends a query struct with the rateLimit field. It returns
+// the struct to query with and a function reading the budget from it, or the
+// response itself and nil when it cannot be extended.
+func withRateLimit(response any) (any, func() rateLimit) {
+	value := reflect.ValueOf(response)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return response, nil
+	}
+
+	queryType := value.Elem().Type()
+	fields := make([]reflect.StructField, 0, queryType.NumField()+1)
+	for i := 0; i < queryType.NumField(); i++ {
+		field := queryType.Field(i)
+		if !field.IsExported() || field.Anonymous || field.Name == "RateLimit" {
+			return response, nil
+		}
+		fields = append(fields, field)
+	}
+	fields = append(fields, reflect.StructField{
+		Name: "RateLimit",
+		Type: reflect.TypeOf(rateLimit{}),
+		Tag:  `graphql:"rateLimit"`,
+	})
+
+	target := reflect.New(reflect.StructOf(fields))
+
+	return target.Interface(), func() rateLimit {
+		return target.Elem().FieldByName("RateLimit").Interface().(rateLimit)
+	}
+}
+
+func copyQueryFields(response, target any) {
+	dst := reflect.ValueOf(response).Elem()
+	src := reflect.ValueOf(target).Elem()
+	for i := 0; i < dst.NumField(); i++ {
+		dst.Field(i).Set(src.Field(i))
+	}
+}
+
+// rateLimitError is a response GitHub asked to retry later, either through
+// Retry-After on a secondary rate limit, or because the budget is exhausted.
+type rateLimitError struct {
+	status int
+	wait   time.Duration
+}
+
+func (e *rateLimitError) Error() string {
+	return fmt.Sprintf("HTTP %d: rate limited, retry in %s", e.status, e.wait.Round(time.Second))
+}
+
+// rateLimitTransport turns rate-limited and 5xx responses into errors before
+// the GraphQL client reads them, keeping the headers that say how long to
+// wait. The GraphQL client only reports the status line of failed requests.
+type rateLimitTransport struct {
+	base http.RoundTripper
+	now  func() time.Time
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if wait, limited := t.retryAfter(resp); limited {
+		resp.Body.Close()
+		return nil, &rateLimitError{status: resp.StatusCode, wait: wait}
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return resp, nil
+}
+
+func (t *rateLimitTransport) retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(t.now()), 0), true
+		}
+		return defaultLimitWait, true
+	}
+
+	return 0, false
+}
+
+// RemainingBudget returns the GraphQL API points left after the last query
+// made through client, when the client keeps track of them.
+func RemainingBudget(client models.GQLClient) (int, bool) {
+	limited, ok := client.(*rateLimitClient)
+	if !ok {
+		return 0, false
+	}
+
+	limited.mu.Lock()
+	defer limited.mu.Unlock()
+
+	return limited.budget.Remaining, limited.known
+}
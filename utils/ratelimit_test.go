@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cli/go-gh/v2/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type cannedResponse struct {
+	status  int
+	headers map[string]string
+	body    string
+}
+
+// cannedTransport answers requests with the given responses in order and
+// records the request bodies.
+type cannedTransport struct {
+	responses []cannedResponse
+	requests  []string
+}
+
+func (t *cannedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	t.requests = append(t.requests, string(body))
+	if len(t.requests) > len(t.responses) {
+		return nil, fmt.Errorf("unexpected request %d", len(t.requests))
+	}
+
+	canned := t.responses[len(t.requests)-1]
+	header := http.Header{"Content-Type": []string{"application/json"}}
+	for key, value := range canned.headers {
+		header.Set(key, value)
+	}
+
+	return &http.Response{
+		StatusCode: canned.status,
+		Status:     fmt.Sprintf("%d %s", canned.status, http.StatusText(canned.status)),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(canned.body)),
+		Request:    req,
+	}, nil
+}
+
+func newTestRateLimitClient(t *testing.T, now time.Time, responses ...cannedResponse) (*rateLimitClient, *cannedTransport, *[]time.Duration, *bytes.Buffer) {
+	canned := &cannedTransport{responses: responses}
+	clock := func() time.Time { return now }
+
+	client, err := api.NewGraphQLClient(api.ClientOptions{
+		Host:         "github.com",
+		AuthToken:    "token",
+		Transport:    &rateLimitTransport{base: canned, now: clock},
+		LogIgnoreEnv: true,
+	})
+	assert.NoError(t, err)
+
+	var sleeps []time.Duration
+	var log bytes.Buffer
+	limited := newRateLimitClient(ghGraphQLClient{client})
+	limited.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return ctx.Err()
+	}
+	limited.now = clock
+	limited.log = &log
+
+	return limited, canned, &sleeps, &log
+}
+
+type viewerQuery struct {
+	Viewer struct {
+		Login string
+	}
+}
+
+func viewerBody(remaining, cost int, resetAt time.Time) string {
+	return fmt.Sprintf(`{"data": {"viewer": {"login": "octocat"}, "rateLimit": {"limit": 5000, "remaining": %d, "cost": %d, "resetAt": %q}}}`,
+		remaining, cost, resetAt.Format(time.RFC3339))
+}
+
+func TestRateLimitClient(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	resetAt := now.Add(10 * time.Minute)
+
+	t.Run("Asks for the budget and fills the caller's struct", func(t *testing.T) {
+		client, canned, sleeps, _ := newTestRateLimitClient(t, now,
+			cannedResponse{status: 200, body: viewerBody(4000, 1, resetAt)},
+		)
+
+		var query viewerQuery
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Equal(t, "octocat", query.Viewer.Login)
+		assert.Contains(t, canned.requests[0], "rateLimit{limit,remaining,cost,resetAt}")
+		assert.Equal(t, 4000, client.budget.Remaining)
+		assert.True(t, client.budget.ResetAt.Equal(resetAt))
+		assert.Empty(t, *sleeps)
+	})
+
+	t.Run("Retries server errors with backoff", func(t *testing.T) {
+		client, canned, sleeps, log := newTestRateLimitClient(t, now,
+			cannedResponse{status: 502, body: `{"message": "Bad Gateway"}`},
+			cannedResponse{status: 200, body: viewerBody(4000, 1, resetAt)},
+		)
+
+		var query viewerQuery
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Equal(t, "octocat", query.Viewer.Login)
+		assert.Len(t, canned.requests, 2)
+		assert.Len(t, *sleeps, 1)
+		assert.GreaterOrEqual(t, (*sleeps)[0], baseRetryDelay/2)
+		assert.LessOrEqual(t, (*sleeps)[0], baseRetryDelay)
+		assert.Contains(t, log.String(), "retrying")
+	})
+
+	t.Run("Waits as long as Retry-After asks on a secondary rate limit", func(t *testing.T) {
+		client, _, sleeps, _ := newTestRateLimitClient(t, now,
+			cannedResponse{status: 403, headers: map[string]string{"Retry-After": "7"}, body: `{"message": "You have exceeded a secondary rate limit"}`},
+			cannedResponse{status: 200, body: viewerBody(4000, 1, resetAt)},
+		)
+
+		var query viewerQuery
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Equal(t, []time.Duration{7 * time.Second}, *sleeps)
+	})
+
+	t.Run("Waits for the reset when the primary limit is exhausted", func(t *testing.T) {
+		reset := fmt.Sprint(now.Add(90 * time.Second).Unix())
+		client, _, sleeps, _ := newTestRateLimitClient(t, now,
+			cannedResponse{status: 403, headers: map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": reset}, body: `{"message": "API rate limit exceeded"}`},
+			cannedResponse{status: 200, body: viewerBody(5000, 1, resetAt)},
+		)
+
+		var query viewerQuery
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Equal(t, []time.Duration{90 * time.Second}, *sleeps)
+	})
+
+	t.Run("Does not retry query errors", func(t *testing.T) {
+		client, canned, sleeps, _ := newTestRateLimitClient(t, now,
+			cannedResponse{status: 200, body: `{"data": null, "errors": [{"message": "Field 'nope' doesn't exist", "type": "undefinedField"}]}`},
+		)
+
+		var query viewerQuery
+		assert.Error(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Len(t, canned.requests, 1)
+		assert.Empty(t, *sleeps)
+	})
+
+	t.Run("Passes on the data returned alongside query errors", func(t *testing.T) {
+		client, _, _, _ := newTestRateLimitClient(t, now,
+			cannedResponse{status: 200, body: fmt.Sprintf(`{"data": {"repository": {"pr1": {"title": "Feat"}, "pr2": null}, "rateLimit": {"limit": 5000, "remaining": 4000, "cost": 1, "resetAt": %q}}, "errors": [{"message": "Could not resolve to a PullRequest with the number of 2.", "type": "NOT_FOUND", "path": ["repository", "pr2"]}]}`, resetAt.Format(time.RFC3339))},
+		)
+
+		var query struct {
+			Repository struct {
+				PR1 *struct{ Title string } `graphql:"pr1: pullRequest(number: 1)"`
+				PR2 *struct{ Title string } `graphql:"pr2: pullRequest(number: 2)"`
+			} `graphql:"repository(owner: \"org\", name: \"repo\")"`
+		}
+		err := client.Query(context.Background(), "PullRequestDetails", &query, nil)
+		assert.Error(t, err)
+		assert.NotNil(t, query.Repository.PR1)
+		assert.Equal(t, "Feat", query.Repository.PR1.Title)
+		assert.Nil(t, query.Repository.PR2)
+		assert.Equal(t, 4000, client.budget.Remaining)
+	})
+
+	t.Run("Gives up after the last retry", func(t *testing.T) {
+		var responses []cannedResponse
+		for i := 0; i <= maxQueryRetries; i++ {
+			responses = append(responses, cannedResponse{status: 503, body: `{"message": "Service Unavailable"}`})
+		}
+		client, canned, sleeps, _ := newTestRateLimitClient(t, now, responses...)
+
+		var query viewerQuery
+		err := client.Query(context.Background(), "ViewerLogin", &query, nil)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "503")
+		assert.Len(t, canned.requests, maxQueryRetries+1)
+		assert.Len(t, *sleeps, maxQueryRetries)
+	})
+
+	t.Run("Warns once when the budget runs low and waits when it runs out", func(t *testing.T) {
+		client, _, sleeps, log := newTestRateLimitClient(t, now,
+			cannedResponse{status: 200, body: viewerBody(400, 1, resetAt)},
+			cannedResponse{status: 200, body: viewerBody(0, 1, resetAt)},
+			cannedResponse{status: 200, body: viewerBody(5000, 1, resetAt.Add(time.Hour))},
+		)
+
+		var query viewerQuery
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Empty(t, *sleeps)
+		assert.Equal(t, 1, strings.Count(log.String(), "running low"))
+
+		assert.NoError(t, client.Query(context.Background(), "ViewerLogin", &query, nil))
+		assert.Equal(t, []time.Duration{10 * time.Minute}, *sleeps)
+		assert.Contains(t, log.String(), "used up")
+	})
+
+	t.Run("Stops retrying once the context is cancelled", func(t *testing.T) {
+		client, canned, sleeps, _ := newTestRateLimitClient(t, now,
+			cannedResponse{status: 502, body: `{"message": "Bad Gateway"}`},
+			cannedResponse{status: 200, body: viewerBody(4000, 1, resetAt)},
+		)
+		ctx, cancel := context.WithCancel(context.Background())
+		client.sleep = func(ctx context.Context, d time.Duration) error {
+			*sleeps = append(*sleeps, d)
+			cancel()
+			return ctx.Err()
+		}
+
+		var query viewerQuery
+		err := client.Query(ctx, "ViewerLogin", &query, nil)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Len(t, canned.requests, 1)
+	})
+}
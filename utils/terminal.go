@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// IsInteractive reports whether stdin and stdout are both terminals. The
+// spinner and the interactive tables need both; when output is piped, or the
+// tool runs from a script or CI, commands print plain output instead.
+func IsInteractive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// IsStderrTerminal reports whether stderr is a terminal, where progress can
+// be shown without ending up in logs or redirected output.
+func IsStderrTerminal() bool {
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}